@@ -0,0 +1,145 @@
+package signal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName is used for AssumeRole when no session name is given
+const DefaultRoleSessionName = "tcsignal-aws"
+
+// AWSOptions controls how AWS clients are configured for publishing.
+// The zero value uses the AWS SDK default chain unchanged.
+type AWSOptions struct {
+	Profile              string
+	RoleARN              string
+	ExternalID           string
+	RoleSessionName      string
+	RoleDuration         time.Duration
+	WebIdentityTokenFile string
+
+	// EndpointURL only applies to the SQS client
+	EndpointURL string
+
+	// Endpoint variants apply to every client, including STS for role
+	// credentials
+	UseFIPSEndpoint      bool
+	UseDualStackEndpoint bool
+
+	// Network options apply to every client built from the configuration
+	HTTPSProxy string
+	NoProxy    string
+	CABundle   string
+}
+
+// loadAWSConfig loads the default AWS configuration and layers the
+// credential options from opts on top of it
+func loadAWSConfig(ctx context.Context, opts AWSOptions, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	loadOptions, err := networkLoadOptions(opts)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if opts.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(opts.Profile))
+	}
+	if opts.UseFIPSEndpoint {
+		loadOptions = append(loadOptions, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+	if opts.UseDualStackEndpoint {
+		loadOptions = append(loadOptions, config.WithUseDualStackEndpoint(aws.DualStackEndpointStateEnabled))
+	}
+	loadOptions = append(loadOptions, optFns...)
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if opts.RoleARN == "" {
+		return awsCfg, nil
+	}
+
+	sessionName := opts.RoleSessionName
+	if sessionName == "" {
+		sessionName = DefaultRoleSessionName
+	}
+	stsClient := sts.NewFromConfig(awsCfg)
+
+	if opts.WebIdentityTokenFile != "" {
+		// Exchange the web identity token (e.g. IRSA or CI OIDC) for role credentials
+		provider := stscreds.NewWebIdentityRoleProvider(stsClient, opts.RoleARN,
+			stscreds.IdentityTokenFile(opts.WebIdentityTokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = sessionName
+				if opts.RoleDuration > 0 {
+					o.Duration = opts.RoleDuration
+				}
+			})
+		awsCfg.Credentials = aws.NewCredentialsCache(provider)
+	} else {
+		// Assume the configured role using the default chain as source credentials
+		provider := stscreds.NewAssumeRoleProvider(stsClient, opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if opts.ExternalID != "" {
+				o.ExternalID = aws.String(opts.ExternalID)
+			}
+			if opts.RoleDuration > 0 {
+				o.Duration = opts.RoleDuration
+			}
+		})
+		awsCfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return awsCfg, nil
+}
+
+// networkLoadOptions builds the HTTP client options for proxies and custom
+// CA bundles so they do not depend on the environment being set in early boot
+func networkLoadOptions(opts AWSOptions) ([]func(*config.LoadOptions) error, error) {
+	var loadOptions []func(*config.LoadOptions) error
+
+	if opts.HTTPSProxy != "" || opts.NoProxy != "" {
+		var proxyURL *url.URL
+		if opts.HTTPSProxy != "" {
+			u, err := url.Parse(opts.HTTPSProxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid HTTPS proxy: %w", err)
+			}
+			proxyURL = u
+		}
+
+		httpClient := awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+			tr.Proxy = proxyFunc(proxyURL, opts.NoProxy)
+		})
+		loadOptions = append(loadOptions, config.WithHTTPClient(httpClient))
+	}
+
+	if opts.CABundle != "" {
+		bundle, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		loadOptions = append(loadOptions, config.WithCustomCABundle(bytes.NewReader(bundle)))
+	}
+
+	return loadOptions, nil
+}
+
+// sqsOptions applies the endpoint URL to an SQS client
+func (o AWSOptions) sqsOptions(opts *sqs.Options) {
+	if o.EndpointURL != "" {
+		opts.BaseEndpoint = aws.String(o.EndpointURL)
+	}
+}
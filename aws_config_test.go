@@ -0,0 +1,209 @@
+package signal
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+func TestLoadAWSConfig_DefaultChain(t *testing.T) {
+	static := credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")
+
+	awsCfg, err := loadAWSConfig(context.Background(), AWSOptions{},
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(static))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !aws.IsCredentialsProvider(awsCfg.Credentials, static) {
+		t.Errorf("Expected default credentials to be used without --role-arn")
+	}
+}
+
+func TestLoadAWSConfig_AssumeRole(t *testing.T) {
+	static := credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")
+
+	awsCfg, err := loadAWSConfig(context.Background(), AWSOptions{
+		RoleARN:    "arn:aws:iam::123456789012:role/signal-publisher",
+		ExternalID: "tooling",
+	},
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(static))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !aws.IsCredentialsProvider(awsCfg.Credentials, &stscreds.AssumeRoleProvider{}) {
+		t.Errorf("Expected assume role credentials, got: %T", awsCfg.Credentials)
+	}
+}
+
+func TestAWSOptions_SQSOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     AWSOptions
+		endpoint string
+	}{
+		{"default", AWSOptions{}, ""},
+		{"endpoint", AWSOptions{EndpointURL: "https://vpce.example.com"}, "https://vpce.example.com"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts sqs.Options
+			tc.opts.sqsOptions(&opts)
+
+			if aws.ToString(opts.BaseEndpoint) != tc.endpoint {
+				t.Errorf("Expected endpoint %q, got: %q", tc.endpoint, aws.ToString(opts.BaseEndpoint))
+			}
+		})
+	}
+}
+
+func TestLoadAWSConfig_EndpointVariants(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts AWSOptions
+		fips aws.FIPSEndpointState
+		dual aws.DualStackEndpointState
+	}{
+		{"default", AWSOptions{}, aws.FIPSEndpointStateUnset, aws.DualStackEndpointStateUnset},
+		{"fips", AWSOptions{UseFIPSEndpoint: true}, aws.FIPSEndpointStateEnabled, aws.DualStackEndpointStateUnset},
+		{"dualstack", AWSOptions{UseDualStackEndpoint: true}, aws.FIPSEndpointStateUnset, aws.DualStackEndpointStateEnabled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsCfg, err := loadAWSConfig(context.Background(), tc.opts,
+				config.WithRegion("us-east-1"),
+				config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			// STS, used for --role-arn, must use the same endpoints as SQS
+			sqsOpts := sqs.NewFromConfig(awsCfg).Options().EndpointOptions
+			stsOpts := sts.NewFromConfig(awsCfg).Options().EndpointOptions
+			for client, opts := range map[string]struct {
+				UseFIPSEndpoint      aws.FIPSEndpointState
+				UseDualStackEndpoint aws.DualStackEndpointState
+			}{
+				"sqs": {sqsOpts.UseFIPSEndpoint, sqsOpts.UseDualStackEndpoint},
+				"sts": {stsOpts.UseFIPSEndpoint, stsOpts.UseDualStackEndpoint},
+			} {
+				if opts.UseFIPSEndpoint != tc.fips {
+					t.Errorf("Expected %s FIPS state %v, got: %v", client, tc.fips, opts.UseFIPSEndpoint)
+				}
+				if opts.UseDualStackEndpoint != tc.dual {
+					t.Errorf("Expected %s dual-stack state %v, got: %v", client, tc.dual, opts.UseDualStackEndpoint)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAWSConfig_ProxyAndCABundle(t *testing.T) {
+	// Use the certificate of a test TLS server as the custom CA bundle
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0o644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	awsCfg, err := loadAWSConfig(context.Background(), AWSOptions{
+		HTTPSProxy: "http://proxy.internal:3128",
+		NoProxy:    "169.254.169.254",
+		CABundle:   bundle,
+	}, config.WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	client, ok := awsCfg.HTTPClient.(*awshttp.BuildableClient)
+	if !ok {
+		t.Fatalf("Expected buildable HTTP client, got: %T", awsCfg.HTTPClient)
+	}
+
+	transport := client.GetTransport()
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.RootCAs == nil {
+		t.Error("Expected custom CA bundle to be configured")
+	}
+
+	req, _ := http.NewRequest("POST", "https://sqs.us-east-1.amazonaws.com/", nil)
+	proxyURL, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("Expected no error from proxy func, got: %v", err)
+	}
+	if proxyURL == nil || proxyURL.Host != "proxy.internal:3128" {
+		t.Errorf("Expected requests to use the proxy, got: %v", proxyURL)
+	}
+}
+
+func TestLoadAWSConfig_MissingCABundle(t *testing.T) {
+	_, err := loadAWSConfig(context.Background(), AWSOptions{
+		CABundle: filepath.Join(t.TempDir(), "missing.pem"),
+	}, config.WithRegion("us-east-1"))
+	if err == nil {
+		t.Fatal("Expected error for missing CA bundle, got nil")
+	}
+}
+
+func TestLoadAWSConfig_WebIdentity(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("oidc-token"), 0o600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	awsCfg, err := loadAWSConfig(context.Background(), AWSOptions{
+		RoleARN:              "arn:aws:iam::123456789012:role/signal-publisher",
+		WebIdentityTokenFile: tokenFile,
+	}, config.WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !aws.IsCredentialsProvider(awsCfg.Credentials, &stscreds.WebIdentityRoleProvider{}) {
+		t.Errorf("Expected web identity credentials, got: %T", awsCfg.Credentials)
+	}
+}
+
+func TestLoadAWSConfig_Profile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "credentials")
+	profile := "[signal-publisher]\naws_access_key_id = AKIDPROFILE\naws_secret_access_key = SECRET\n"
+	if err := os.WriteFile(configFile, []byte(profile), 0o600); err != nil {
+		t.Fatalf("Failed to write credentials file: %v", err)
+	}
+
+	awsCfg, err := loadAWSConfig(context.Background(), AWSOptions{Profile: "signal-publisher"},
+		config.WithRegion("us-east-1"),
+		config.WithSharedConfigFiles([]string{}),
+		config.WithSharedCredentialsFiles([]string{configFile}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	creds, err := awsCfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Expected credentials from profile, got: %v", err)
+	}
+
+	if creds.AccessKeyID != "AKIDPROFILE" {
+		t.Errorf("Expected access key from profile, got: %s", creds.AccessKeyID)
+	}
+}
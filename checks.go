@@ -0,0 +1,124 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Check is a named shell command that passes when it exits with code 0
+type Check struct {
+	Name    string
+	Command string
+}
+
+// ParseCheck parses a NAME=CMD check definition
+func ParseCheck(s string) (Check, error) {
+	name, command, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(command) == "" {
+		return Check{}, fmt.Errorf("invalid check %q (expected NAME=CMD)", s)
+	}
+	if !stepNamePattern.MatchString(name) {
+		return Check{}, fmt.Errorf("invalid check name %q (use letters, digits, '.', '_' or '-')", name)
+	}
+	return Check{Name: name, Command: command}, nil
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name   string
+	Passed bool
+	// Reason explains a failed check, including the last line of its output
+	Reason   string
+	Duration time.Duration
+}
+
+// CheckRunner runs checks concurrently
+type CheckRunner struct {
+	Executor Executor
+	Logger   Logger
+	// Concurrency limits how many checks run at once; values below 1 run
+	// one at a time
+	Concurrency int
+	// Timeout bounds each check. Zero means no limit.
+	Timeout time.Duration
+	// Spec returns the command to run for a check, e.g. to apply the
+	// user and environment options; nil runs the command as-is
+	Spec func(Check) ExecSpec
+}
+
+// Run runs every check and returns their results in the order given
+func (r *CheckRunner) Run(ctx context.Context, checks []Check) []CheckResult {
+	results := make([]CheckResult, len(checks))
+	sem := make(chan struct{}, max(r.Concurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = r.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (r *CheckRunner) run(ctx context.Context, check Check) CheckResult {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	spec := ExecSpec{Command: check.Command}
+	if r.Spec != nil {
+		spec = r.Spec(check)
+	}
+	output := &LastLineWriter{}
+	spec.Stdout = output
+	spec.Stderr = output
+
+	r.Logger.Debug("Running check", zap.String("check", check.Name), zap.Stringer("command", spec))
+
+	result := CheckResult{Name: check.Name}
+	execResult, err := r.Executor.Run(ctx, spec)
+	result.Duration = execResult.Duration
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Reason = fmt.Sprintf("timed out after %s", r.Timeout)
+	case err != nil:
+		result.Reason = err.Error()
+	case execResult.Signal != "":
+		result.Reason = "terminated by " + execResult.Signal
+	case execResult.ExitCode != 0:
+		result.Reason = fmt.Sprintf("exited with code %d", execResult.ExitCode)
+	default:
+		result.Passed = true
+	}
+	if line := output.Line(); !result.Passed && line != "" {
+		result.Reason += ": " + line
+	}
+
+	if result.Passed {
+		r.Logger.Info("Check passed",
+			zap.String("check", check.Name),
+			zap.Duration("duration", result.Duration))
+	} else {
+		r.Logger.Warn("Check failed",
+			zap.String("check", check.Name),
+			zap.String("reason", result.Reason),
+			zap.Duration("duration", result.Duration))
+	}
+
+	return result
+}
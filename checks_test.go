@@ -0,0 +1,89 @@
+package signal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCheck(t *testing.T) {
+	check, err := ParseCheck("disk=mountpoint -q /data")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if check.Name != "disk" || check.Command != "mountpoint -q /data" {
+		t.Errorf("Unexpected check: %+v", check)
+	}
+
+	// Only the first = separates the name
+	check, err = ParseCheck("env=test \"$A\" = b")
+	if err != nil || check.Command != "test \"$A\" = b" {
+		t.Errorf("Expected command to keep later '=', got: %+v, %v", check, err)
+	}
+
+	for _, invalid := range []string{"disk", "disk=", "=true", "bad name=true"} {
+		if _, err := ParseCheck(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestCheckRunner(t *testing.T) {
+	runner := &CheckRunner{
+		Executor:    NewDefaultExecutor(createTestLogger()),
+		Logger:      createTestLogger(),
+		Concurrency: 3,
+		Timeout:     200 * time.Millisecond,
+	}
+
+	results := runner.Run(context.Background(), []Check{
+		{Name: "pass", Command: "true"},
+		{Name: "fail", Command: "echo agent not registered >&2; exit 3"},
+		{Name: "slow", Command: "sleep 5"},
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got: %d", len(results))
+	}
+
+	if results[0].Name != "pass" || !results[0].Passed {
+		t.Errorf("Expected pass to pass, got: %+v", results[0])
+	}
+
+	if results[1].Passed || results[1].Reason != "exited with code 3: agent not registered" {
+		t.Errorf("Expected fail to fail with its output, got: %+v", results[1])
+	}
+
+	if results[2].Passed || !strings.HasPrefix(results[2].Reason, "timed out after 200ms") {
+		t.Errorf("Expected slow to time out, got: %+v", results[2])
+	}
+}
+
+func TestCheckRunner_Concurrency(t *testing.T) {
+	runner := &CheckRunner{
+		Executor:    NewDefaultExecutor(createTestLogger()),
+		Logger:      createTestLogger(),
+		Concurrency: 2,
+	}
+
+	checks := make([]Check, 4)
+	for i := range checks {
+		checks[i] = Check{Name: "sleep", Command: "sleep 0.2"}
+	}
+
+	// Four 200ms checks, two at a time, take about 400ms
+	start := time.Now()
+	results := runner.Run(context.Background(), checks)
+	elapsed := time.Since(start)
+
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("Expected check to pass, got: %+v", r)
+		}
+	}
+
+	if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected checks to run two at a time, took: %v", elapsed)
+	}
+}
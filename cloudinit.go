@@ -0,0 +1,191 @@
+package signal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Paths cloud-init writes once it has finished, relative to the root
+const (
+	cloudInitBootFinished = "var/lib/cloud/instance/boot-finished"
+	cloudInitResult       = "run/cloud-init/result.json"
+	cloudInitStatus       = "run/cloud-init/status.json"
+)
+
+// CloudInitResult is the outcome of a cloud-init run
+type CloudInitResult struct {
+	Datasource string
+	// Errors lists every error reported by cloud-init, prefixed with the
+	// stage it came from when known
+	Errors []string
+}
+
+// Failed reports whether cloud-init reported any errors
+func (r *CloudInitResult) Failed() bool {
+	return len(r.Errors) > 0
+}
+
+// Reason summarises the errors for a signal reason
+func (r *CloudInitResult) Reason() string {
+	if !r.Failed() {
+		return ""
+	}
+	return "cloud-init failed: " + strings.Join(r.Errors, "; ")
+}
+
+// CloudInitWaiter waits for cloud-init to finish and reads its result
+type CloudInitWaiter struct {
+	// Root is prepended to cloud-init's paths; empty means "/"
+	Root     string
+	Interval time.Duration
+	Logger   Logger
+}
+
+func NewCloudInitWaiter(logger Logger) *CloudInitWaiter {
+	return &CloudInitWaiter{
+		Root:     "/",
+		Interval: 2 * time.Second,
+		Logger:   logger,
+	}
+}
+
+// cloudInitResultFile is the layout of result.json
+type cloudInitResultFile struct {
+	V1 struct {
+		Datasource *string  `json:"datasource"`
+		Errors     []string `json:"errors"`
+	} `json:"v1"`
+}
+
+// cloudInitStages are the stages in status.json in the order they run
+var cloudInitStages = []string{"init-local", "init", "modules-config", "modules-final"}
+
+// cloudInitStage is one stage entry in status.json
+type cloudInitStage struct {
+	Errors []string `json:"errors"`
+}
+
+// Wait polls until cloud-init has written boot-finished and result.json,
+// then returns its result. It returns an error if ctx is done first or the
+// result cannot be read.
+func (w *CloudInitWaiter) Wait(ctx context.Context) (*CloudInitResult, error) {
+	start := time.Now()
+	for {
+		result, err := w.Result()
+		if err == nil {
+			w.Logger.Info("cloud-init finished",
+				zap.Duration("elapsed", time.Since(start)),
+				zap.Int("errors", len(result.Errors)))
+			return result, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		w.Logger.Debug("Waiting for cloud-init to finish", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("cloud-init did not finish: %w", ctx.Err())
+		case <-time.After(w.Interval):
+		}
+	}
+}
+
+// Result reads the result of a finished cloud-init run. The error wraps
+// os.ErrNotExist if cloud-init has not finished.
+func (w *CloudInitWaiter) Result() (*CloudInitResult, error) {
+	if _, err := os.Stat(w.path(cloudInitBootFinished)); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(w.path(cloudInitResult))
+	if err != nil {
+		return nil, err
+	}
+
+	var resultFile cloudInitResultFile
+	if err := json.Unmarshal(data, &resultFile); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", cloudInitResult, err)
+	}
+
+	result := &CloudInitResult{}
+	if resultFile.V1.Datasource != nil {
+		result.Datasource = *resultFile.V1.Datasource
+	}
+
+	// Per-stage errors from status.json say where each error came from
+	stageErrors, err := w.stageErrors()
+	if err != nil {
+		w.Logger.Debug("Failed to read cloud-init stage status", zap.Error(err))
+	}
+	if len(stageErrors) > 0 {
+		result.Errors = stageErrors
+	} else {
+		result.Errors = resultFile.V1.Errors
+	}
+
+	return result, nil
+}
+
+// stageErrors reads the errors of each stage from status.json, in the
+// order the stages run
+func (w *CloudInitWaiter) stageErrors() ([]string, error) {
+	data, err := os.ReadFile(w.path(cloudInitStatus))
+	if err != nil {
+		return nil, err
+	}
+
+	var status struct {
+		V1 map[string]json.RawMessage `json:"v1"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", cloudInitStatus, err)
+	}
+
+	// Known stages first, then any others a newer cloud-init may add
+	var others []string
+	for name := range status.V1 {
+		if !slices.Contains(cloudInitStages, name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	stages := append(slices.Clone(cloudInitStages), others...)
+
+	var errs []string
+	for _, name := range stages {
+		// Non-stage keys such as "datasource" and "stage" do not decode
+		raw, ok := status.V1[name]
+		if !ok {
+			continue
+		}
+		var stage cloudInitStage
+		if err := json.Unmarshal(raw, &stage); err != nil {
+			continue
+		}
+		for _, e := range stage.Errors {
+			errs = append(errs, name+": "+e)
+		}
+	}
+
+	return errs, nil
+}
+
+func (w *CloudInitWaiter) path(rel string) string {
+	root := w.Root
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(root, rel)
+}
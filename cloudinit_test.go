@@ -0,0 +1,179 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const cloudInitResultJSON = `{
+  "v1": {
+    "datasource": "DataSourceEc2Local",
+    "errors": [
+      "('scripts_user', RuntimeError('Runparts: 1 failures (part-001) in 1 attempted commands'))"
+    ]
+  }
+}`
+
+const cloudInitStatusJSON = `{
+  "v1": {
+    "datasource": "DataSourceEc2Local",
+    "init": {"errors": [], "finished": 1700000010.1, "start": 1700000005.2},
+    "init-local": {"errors": [], "finished": 1700000004.9, "start": 1700000001.0},
+    "modules-config": {"errors": ["('ntp', ValueError('bad server'))"], "finished": 1700000020.3, "start": 1700000011.0},
+    "modules-final": {"errors": ["('scripts_user', RuntimeError('Runparts: 1 failures (part-001) in 1 attempted commands'))"], "finished": 1700000060.0, "start": 1700000021.0},
+    "stage": null
+  }
+}`
+
+// writeCloudInitFiles writes the files cloud-init leaves behind once it has
+// finished under root. Empty content skips a file.
+func writeCloudInitFiles(t *testing.T, root, result, status string) {
+	t.Helper()
+
+	files := map[string]string{
+		cloudInitBootFinished: "1700000060.00 - v. 23.4\n",
+		cloudInitResult:       result,
+		cloudInitStatus:       status,
+	}
+	for rel, content := range files {
+		if content == "" {
+			continue
+		}
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestCloudInitWaiter_Success(t *testing.T) {
+	root := t.TempDir()
+	writeCloudInitFiles(t, root, `{"v1": {"datasource": "DataSourceEc2Local", "errors": []}}`, "")
+
+	waiter := NewCloudInitWaiter(createTestLogger())
+	waiter.Root = root
+
+	result, err := waiter.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Failed() || result.Reason() != "" {
+		t.Errorf("Expected a successful result, got: %+v", result)
+	}
+
+	if result.Datasource != "DataSourceEc2Local" {
+		t.Errorf("Expected datasource DataSourceEc2Local, got: %s", result.Datasource)
+	}
+}
+
+func TestCloudInitWaiter_StageErrors(t *testing.T) {
+	root := t.TempDir()
+	writeCloudInitFiles(t, root, cloudInitResultJSON, cloudInitStatusJSON)
+
+	waiter := NewCloudInitWaiter(createTestLogger())
+	waiter.Root = root
+
+	result, err := waiter.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []string{
+		"modules-config: ('ntp', ValueError('bad server'))",
+		"modules-final: ('scripts_user', RuntimeError('Runparts: 1 failures (part-001) in 1 attempted commands'))",
+	}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("Expected errors %q, got: %q", expected, result.Errors)
+	}
+
+	if !result.Failed() {
+		t.Error("Expected result to have failed")
+	}
+
+	expectedReason := "cloud-init failed: " + expected[0] + "; " + expected[1]
+	if result.Reason() != expectedReason {
+		t.Errorf("Expected reason %q, got: %q", expectedReason, result.Reason())
+	}
+}
+
+func TestCloudInitWaiter_ResultErrorsWithoutStatus(t *testing.T) {
+	root := t.TempDir()
+	writeCloudInitFiles(t, root, cloudInitResultJSON, "")
+
+	waiter := NewCloudInitWaiter(createTestLogger())
+	waiter.Root = root
+
+	result, err := waiter.Result()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.Errors) != 1 || result.Errors[0] != "('scripts_user', RuntimeError('Runparts: 1 failures (part-001) in 1 attempted commands'))" {
+		t.Errorf("Expected errors from result.json, got: %q", result.Errors)
+	}
+}
+
+func TestCloudInitWaiter_WaitsForFinish(t *testing.T) {
+	root := t.TempDir()
+	writeCloudInitFiles(t, root, `{"v1": {"errors": []}}`, "")
+
+	// cloud-init has not finished this boot until boot-finished is written
+	bootFinished := filepath.Join(root, cloudInitBootFinished)
+	if err := os.Remove(bootFinished); err != nil {
+		t.Fatalf("Failed to remove boot-finished: %v", err)
+	}
+
+	waiter := NewCloudInitWaiter(createTestLogger())
+	waiter.Root = root
+	waiter.Interval = 10 * time.Millisecond
+
+	if _, err := waiter.Result(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected not-exist error before cloud-init finishes, got: %v", err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() {
+		os.WriteFile(bootFinished, nil, 0o644)
+	})
+
+	result, err := waiter.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Failed() {
+		t.Errorf("Expected a successful result, got: %+v", result)
+	}
+}
+
+func TestCloudInitWaiter_Timeout(t *testing.T) {
+	waiter := NewCloudInitWaiter(createTestLogger())
+	waiter.Root = t.TempDir()
+	waiter.Interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := waiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got: %v", err)
+	}
+}
+
+func TestCloudInitWaiter_InvalidResult(t *testing.T) {
+	root := t.TempDir()
+	writeCloudInitFiles(t, root, "{not json", "")
+
+	waiter := NewCloudInitWaiter(createTestLogger())
+	waiter.Root = root
+
+	if _, err := waiter.Wait(context.Background()); err == nil {
+		t.Error("Expected parse error, got nil")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/terraconstructs/signal-aws"
+	"go.uber.org/zap"
+)
+
+// heartbeat publishes IN_PROGRESS signals while a command runs so a waiter
+// can tell a long-running command from a dead instance
+type heartbeat struct {
+	cfg        signal.Config
+	publisher  signal.Publisher
+	imdsClient signal.IMDSClient
+	target     *target
+	logger     signal.Logger
+}
+
+// newHeartbeat returns nil when --heartbeat is not set
+func newHeartbeat(cfg signal.Config, publisher signal.Publisher, imdsClient signal.IMDSClient, tgt *target, logger signal.Logger) *heartbeat {
+	if cfg.Heartbeat <= 0 {
+		return nil
+	}
+
+	return &heartbeat{
+		cfg:        cfg,
+		publisher:  publisher,
+		imdsClient: imdsClient,
+		target:     tgt,
+		logger:     logger,
+	}
+}
+
+// start publishes heartbeats for signalID until the returned function is
+// called. With --heartbeat-output, spec's output is also captured so each
+// heartbeat carries the last line as its reason. A nil heartbeat does
+// nothing.
+func (h *heartbeat) start(ctx context.Context, signalID string, spec *signal.ExecSpec) (stop func()) {
+	if h == nil {
+		return func() {}
+	}
+
+	var output *signal.LastLineWriter
+	if h.cfg.HeartbeatOutput {
+		output = &signal.LastLineWriter{}
+		spec.Stdout = io.MultiWriter(writerOrDefault(spec.Stdout, os.Stdout), output)
+		spec.Stderr = io.MultiWriter(writerOrDefault(spec.Stderr, os.Stderr), output)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(h.cfg.Heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var reason string
+				if output != nil {
+					reason = output.Line()
+				}
+				h.publish(ctx, signalID, reason)
+			}
+		}
+	}()
+
+	// Wait for an in-flight heartbeat so it cannot land after the final status
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// publish sends a single IN_PROGRESS signal. Failures are logged and
+// otherwise ignored; the final status is what matters.
+func (h *heartbeat) publish(ctx context.Context, signalID, reason string) {
+	if h.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.Timeout)
+		defer cancel()
+	}
+
+	if err := h.target.resolve(ctx, h.cfg, h.imdsClient, h.logger); err != nil {
+		h.logger.Warn("Failed to publish heartbeat", zap.Error(err), zap.String("signal_id", signalID))
+		return
+	}
+
+	publishInput := signal.PublishInput{
+		QueueURL:       h.cfg.QueueURL,
+		SignalID:       signalID,
+		InstanceID:     h.target.instanceID,
+		Status:         signal.StatusInProgress,
+		Reason:         reason,
+		Region:         h.target.region,
+		PublishTimeout: h.cfg.PublishTimeout,
+		Retries:        h.cfg.Retries,
+		AWS:            h.cfg.AWS,
+	}
+
+	if err := h.publisher.Publish(ctx, publishInput); err != nil {
+		h.logger.Warn("Failed to publish heartbeat", zap.Error(err), zap.String("signal_id", signalID))
+		return
+	}
+
+	h.logger.Debug("Published heartbeat",
+		zap.String("signal_id", signalID),
+		zap.String("instance_id", h.target.instanceID))
+}
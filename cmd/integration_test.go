@@ -138,7 +138,7 @@ func TestSQSPublisher_Integration_ElasticMQPublish(t *testing.T) {
 			},
 			"status": {
 				DataType:    aws.String("String"),
-				StringValue: aws.String(input.Status),
+				StringValue: aws.String(string(input.Status)),
 			},
 		},
 	}
@@ -171,7 +171,7 @@ func TestSQSPublisher_Integration_ElasticMQPublish(t *testing.T) {
 	}
 
 	if msg.MessageAttributes["status"].StringValue == nil ||
-		*msg.MessageAttributes["status"].StringValue != input.Status {
+		*msg.MessageAttributes["status"].StringValue != string(input.Status) {
 		t.Errorf("Expected status '%s', got %v", input.Status, msg.MessageAttributes["status"])
 	}
 
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	ossignal "os/signal"
+	"sync"
+
+	"github.com/terraconstructs/signal-aws"
+)
+
+// interruptedError is why the run was cancelled when a termination signal
+// was received
+type interruptedError struct {
+	signal string
+}
+
+func (e *interruptedError) Error() string {
+	return "interrupted by " + e.signal
+}
+
+// interrupts cancels the run when a termination signal is received, so
+// that a retry delay, readiness probes or the cloud-init wait stop and
+// CANCELLED is signalled instead of this process being killed. A command
+// running when the signal arrives has it forwarded by the executor, and
+// the run is cancelled once the command has exited.
+type interrupts struct {
+	signal.Executor
+	cancel  context.CancelCauseFunc
+	signals chan os.Signal
+	done    chan struct{}
+
+	mu       sync.Mutex
+	running  int
+	received string
+}
+
+// handleInterrupts handles termination signals until stop is called. The
+// returned context is cancelled by them, and commands must be run with the
+// returned executor.
+func handleInterrupts(ctx context.Context, executor signal.Executor) (context.Context, *interrupts) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	i := &interrupts{
+		Executor: executor,
+		cancel:   cancel,
+		signals:  make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+	}
+	ossignal.Notify(i.signals, signal.TerminationSignals...)
+	go i.watch()
+	return ctx, i
+}
+
+func (i *interrupts) watch() {
+	for {
+		select {
+		case sig := <-i.signals:
+			i.mu.Lock()
+			if i.received == "" {
+				i.received = signal.SignalName(sig)
+			}
+			if i.running == 0 {
+				i.cancel(&interruptedError{signal: i.received})
+			}
+			i.mu.Unlock()
+		case <-i.done:
+			return
+		}
+	}
+}
+
+// Run runs a command, leaving a termination signal received meanwhile to
+// the executor
+func (i *interrupts) Run(ctx context.Context, spec signal.ExecSpec) (signal.ExecResult, error) {
+	i.mu.Lock()
+	i.running++
+	i.mu.Unlock()
+
+	defer func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.running--
+		if i.running == 0 && i.received != "" {
+			i.cancel(&interruptedError{signal: i.received})
+		}
+	}()
+
+	return i.Executor.Run(ctx, spec)
+}
+
+// stop restores the default handling of termination signals
+func (i *interrupts) stop() {
+	ossignal.Stop(i.signals)
+	close(i.done)
+	i.cancel(nil)
+}
+
+// interruption returns the status and reason to signal if ctx was cancelled
+// by a termination signal
+func interruption(ctx context.Context) (signal.Status, string, bool) {
+	var err *interruptedError
+	if errors.As(context.Cause(ctx), &err) {
+		return signal.StatusCancelled, err.Error(), true
+	}
+	return "", "", false
+}
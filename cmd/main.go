@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/terraconstructs/signal-aws"
 	"go.uber.org/zap"
 )
 
 func main() {
+	// This executable is re-executed to apply --exec-nice and other limits
+	if signal.RunLimitsShim() {
+		os.Exit(127)
+	}
+
 	cfg, err := signal.ParseConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -24,13 +31,28 @@ func main() {
 	}
 	defer logger.Sync()
 
-	// Set up overall timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
-	defer cancel()
+	ctx := context.Background()
 
 	// Create component instances
 	executor := signal.NewDefaultExecutor(logger)
-	publisher := signal.NewSQSPublisher(logger)
+	executor.KillGrace = cfg.KillGrace
+	executor.Limits = cfg.ExecLimits()
+	executor.ForwardSignals = signal.TerminationSignals
+	if cfg.Supervise {
+		// Stop signals end the service, others are passed through, and as
+		// PID 1 in a container orphaned processes must be reaped
+		executor.ForwardSignals = signal.StopSignals
+		executor.RelaySignals = signal.ReloadSignals
+		if os.Getpid() == 1 {
+			reaper := signal.StartReaper(logger)
+			defer reaper.Stop()
+			executor.Reaper = reaper
+		}
+	}
+	var publisher signal.Publisher = signal.NewSQSPublisher(logger)
+	if cfg.DryRun != signal.DryRunOff {
+		publisher = signal.NewDryRunPublisher(logger, os.Stdout)
+	}
 	imdsClient := signal.NewDefaultIMDSClient()
 
 	result, err := run(ctx, *cfg, executor, publisher, imdsClient, logger)
@@ -46,7 +68,7 @@ func main() {
 }
 
 type RunResult struct {
-	Status     string
+	Status     signal.Status
 	ShouldExit bool
 	ExitCode   int
 }
@@ -57,25 +79,154 @@ func run(ctx context.Context, cfg signal.Config, executor signal.Executor, publi
 		ExitCode:   0,
 	}
 
+	tgt := &target{}
+	runner := &commandRunner{
+		cfg:       cfg,
+		executor:  executor,
+		heartbeat: newHeartbeat(cfg, publisher, imdsClient, tgt, logger),
+		target:    tgt,
+		logger:    logger,
+	}
+
+	executes := cfg.Status == "" && cfg.DryRun != signal.DryRunNoExec
+	if executes && cfg.ExecLogFile != "" {
+		// The signal matters more than the log file, so run the command anyway
+		logFile, err := signal.OpenRotatingFile(cfg.ExecLogFile, int64(cfg.ExecLogMaxSize)*1024*1024, cfg.ExecLogMaxBackups)
+		if err != nil {
+			logger.Warn("Failed to open exec log file", zap.Error(err))
+		} else {
+			defer logFile.Close()
+			runner.logFile = logFile
+		}
+	}
+
+	// The command's environment and output lines carry the instance ID and
+	// region, so look them up first. A failure here is retried when
+	// publishing.
+	if executes && (cfg.Exec != "" || len(cfg.Args) > 0 || len(cfg.Steps) > 0) {
+		resolveCtx := ctx
+		if cfg.Timeout > 0 {
+			var cancel context.CancelFunc
+			resolveCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+		}
+		if err := tgt.resolve(resolveCtx, cfg, imdsClient, logger); err != nil {
+			logger.Warn("Failed to resolve instance ID for the command", zap.Error(err))
+		}
+	}
+
+	if cfg.Supervise {
+		return runner.supervise(ctx, publisher, imdsClient)
+	}
+
+	// From here on a termination signal cancels runCtx rather than killing
+	// this process, so that CANCELLED is still signalled. Signals are
+	// published with ctx.
+	runCtx, interrupts := handleInterrupts(ctx, executor)
+	defer interrupts.stop()
+	executor, runner.executor = interrupts, interrupts
+
 	// Determine status
 	status := cfg.Status
-	if status == "" {
+	var reason string
+	var data map[string]any
+	var (
+		stepErr        error
+		stepsSignalled bool
+	)
+	if status == "" && len(cfg.Steps) > 0 {
+		// With --signal-per-step, each step is signalled as soon as it
+		// finishes so waiters on it are not held up by later steps. The
+		// remaining steps still run if publishing fails.
+		stepDone := func(stepSignal) {}
+		if cfg.SignalPerStep {
+			stepsSignalled = true
+			stepDone = func(sig stepSignal) {
+				if err := publishSignals(ctx, cfg, publisher, imdsClient, tgt, logger, []stepSignal{sig}); err != nil {
+					logger.Error("Failed to publish step signal", zap.Error(err), zap.String("signal_id", sig.id))
+					if stepErr == nil {
+						stepErr = err
+					}
+				}
+			}
+		}
+		status, reason, data = runner.runSteps(runCtx, stepDone)
+
+		// Mark that we should exit with code 1 for failures
+		if status.Failed() {
+			result.ShouldExit = true
+			result.ExitCode = 1
+		}
+	} else if status == "" && cfg.DryRun == signal.DryRunNoExec {
+		logger.Info("Dry run: skipping command execution",
+			zap.Stringer("command", cfg.ExecSpec()),
+			zap.String("signal_id", cfg.ID))
+		status = signal.StatusSuccess
+	} else if status == "" && (cfg.Exec != "" || len(cfg.Args) > 0) {
 		// Execute command and determine status from exit code
-		exitCode, err := executor.Run(cfg.Exec)
-		if err != nil {
-			logger.Error("Command execution failed",
-				zap.String("command", cfg.Exec),
-				zap.Error(err),
-				zap.String("signal_id", cfg.ID))
-			status = "FAILURE"
-		} else if exitCode == 0 {
-			status = "SUCCESS"
+		status, reason, data, _ = runner.execute(runCtx, cfg.ExecSpec(), cfg.ID)
+
+		// Mark that we should exit with code 1 for failures
+		if status.Failed() {
+			result.ShouldExit = true
+			result.ExitCode = 1
+		}
+	}
+
+	// Signal the result of cloud-init instead of a command
+	if status == "" && cfg.WaitCloudInit && cfg.DryRun != signal.DryRunNoExec {
+		status, reason = waitCloudInit(runCtx, cfg, logger)
+		if status.Failed() {
+			result.ShouldExit = true
+			result.ExitCode = 1
+		}
+	}
+
+	// Wait for readiness probes once the command has succeeded, or on their
+	// own. verified records that probes or checks ran.
+	var verified bool
+	if probes := cfg.Probes(executor); len(probes) > 0 && cfg.DryRun != signal.DryRunNoExec && (status == "" || status == signal.StatusSuccess) {
+		logger.Info("Waiting for readiness probes",
+			zap.Int("probes", len(probes)),
+			zap.Duration("wait_timeout", cfg.WaitTimeout),
+			zap.String("signal_id", cfg.ID))
+
+		verified = true
+		if err := signal.WaitReady(runCtx, probes, cfg.WaitInterval, cfg.WaitTimeout, logger); err != nil {
+			logger.Error("Readiness probes failed", zap.Error(err), zap.String("signal_id", cfg.ID))
+			status = signal.StatusFailure
+			if errors.Is(err, signal.ErrProbeTimeout) {
+				status = signal.StatusTimeout
+			}
+			reason = err.Error()
+			result.ShouldExit = true
+			result.ExitCode = 1
 		} else {
-			status = "FAILURE"
+			status = signal.StatusSuccess
 		}
+	}
 
-		// Mark that we should exit with code 1 for failures
-		if status == "FAILURE" {
+	// Run checks once everything before them has succeeded, or on their own
+	if len(cfg.Checks) > 0 && cfg.DryRun != signal.DryRunNoExec && (status == "" || status == signal.StatusSuccess) {
+		verified = true
+		var checkData map[string]any
+		status, reason, checkData = runChecks(runCtx, cfg, executor, logger)
+		if data == nil {
+			data = make(map[string]any)
+		}
+		data["checks"] = checkData
+		if status.Failed() {
+			result.ShouldExit = true
+			result.ExitCode = 1
+		}
+	}
+
+	// A termination signal stops whatever the run was waiting for, which
+	// then failed because of it
+	if status.Failed() && status != signal.StatusCancelled {
+		if cancelled, why, ok := interruption(runCtx); ok {
+			logger.Error("Run interrupted", zap.String("reason", why), zap.String("signal_id", cfg.ID))
+			status, reason = cancelled, why
 			result.ShouldExit = true
 			result.ExitCode = 1
 		}
@@ -83,56 +234,212 @@ func run(ctx context.Context, cfg signal.Config, executor signal.Executor, publi
 
 	result.Status = status
 
+	if stepErr != nil {
+		return result, stepErr
+	}
+
+	// Publish one aggregate signal, unless each step has been signalled.
+	// Probes and checks run after the steps, so their result is only in the
+	// aggregate signal, which is then sent as well.
+	if stepsSignalled && !verified {
+		return result, nil
+	}
+	signals := []stepSignal{{id: cfg.ID, status: status, reason: reason, data: data}}
+	if err := publishSignals(ctx, cfg, publisher, imdsClient, tgt, logger, signals); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// publishSignals resolves the instance ID and region, unless a heartbeat
+// already did, and publishes each signal. Both are bounded by --timeout.
+func publishSignals(ctx context.Context, cfg signal.Config, publisher signal.Publisher, imdsClient signal.IMDSClient, tgt *target, logger signal.Logger, signals []stepSignal) error {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	if err := tgt.resolve(ctx, cfg, imdsClient, logger); err != nil {
+		return err
+	}
+
+	for _, sig := range signals {
+		publishInput := signal.PublishInput{
+			QueueURL:       cfg.QueueURL,
+			SignalID:       sig.id,
+			InstanceID:     tgt.instanceID,
+			Status:         sig.status,
+			Reason:         sig.reason,
+			Data:           sig.data,
+			Region:         tgt.region,
+			PublishTimeout: cfg.PublishTimeout,
+			Retries:        cfg.Retries,
+			AWS:            cfg.AWS,
+		}
+
+		if err := publisher.Publish(ctx, publishInput); err != nil {
+			if cfg.SignalPerStep {
+				err = fmt.Errorf("%s: %w", sig.id, err)
+			}
+			return fmt.Errorf("failed to publish signal: %w", err)
+		}
+
+		if cfg.DryRun != signal.DryRunOff {
+			continue
+		}
+
+		logger.Info("Successfully published signal",
+			zap.Stringer("status", sig.status),
+			zap.String("signal_id", sig.id),
+			zap.String("instance_id", tgt.instanceID))
+	}
+
+	return nil
+}
+
+// waitCloudInit waits up to --wait-timeout for cloud-init to finish and
+// returns the status and reason to signal for its result
+func waitCloudInit(ctx context.Context, cfg signal.Config, logger signal.Logger) (status signal.Status, reason string) {
+	waiter := signal.NewCloudInitWaiter(logger)
+	waiter.Root = cfg.CloudInitRoot
+	waiter.Interval = cfg.WaitInterval
+
+	if cfg.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.WaitTimeout)
+		defer cancel()
+	}
+
+	logger.Info("Waiting for cloud-init to finish",
+		zap.Duration("wait_timeout", cfg.WaitTimeout),
+		zap.String("signal_id", cfg.ID))
+
+	ciResult, err := waiter.Wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		logger.Error("cloud-init did not finish in time", zap.Duration("wait_timeout", cfg.WaitTimeout))
+		return signal.StatusTimeout, fmt.Sprintf("cloud-init did not finish within %s", cfg.WaitTimeout)
+	} else if err != nil {
+		logger.Error("Failed to read cloud-init result", zap.Error(err))
+		return signal.StatusFailure, err.Error()
+	}
+
+	if ciResult.Failed() {
+		logger.Error("cloud-init reported errors",
+			zap.Strings("errors", ciResult.Errors),
+			zap.String("signal_id", cfg.ID))
+		return signal.StatusFailure, ciResult.Reason()
+	}
+
+	return signal.StatusSuccess, ""
+}
+
+// runChecks runs --check commands concurrently and returns the status,
+// reason and per-check data to signal. The status is SUCCESS when at least
+// --min-pass checks pass, or all of them when --min-pass is not set.
+func runChecks(ctx context.Context, cfg signal.Config, executor signal.Executor, logger signal.Logger) (status signal.Status, reason string, data map[string]any) {
+	required := cfg.MinPass
+	if required == 0 {
+		required = len(cfg.Checks)
+	}
+
+	logger.Info("Running checks",
+		zap.Int("checks", len(cfg.Checks)),
+		zap.Int("required", required),
+		zap.Int("concurrency", cfg.CheckConcurrency),
+		zap.String("signal_id", cfg.ID))
+
+	runner := &signal.CheckRunner{
+		Executor:    executor,
+		Logger:      logger,
+		Concurrency: cfg.CheckConcurrency,
+		Timeout:     cfg.CheckTimeout,
+		Spec:        cfg.CheckExecSpec,
+	}
+	results := runner.Run(ctx, cfg.Checks)
+
+	var (
+		passed   int
+		failures []string
+	)
+	data = make(map[string]any, len(results))
+	for _, r := range results {
+		checkData := map[string]any{
+			"passed":           r.Passed,
+			"duration_seconds": seconds(r.Duration),
+		}
+		if r.Passed {
+			passed++
+		} else {
+			checkData["reason"] = r.Reason
+			failures = append(failures, fmt.Sprintf("%s: %s", r.Name, r.Reason))
+		}
+		data[r.Name] = checkData
+	}
+
+	if passed >= required {
+		if len(failures) > 0 {
+			logger.Warn("Some checks failed but enough passed",
+				zap.Int("passed", passed),
+				zap.Int("required", required),
+				zap.String("signal_id", cfg.ID))
+		}
+		return signal.StatusSuccess, "", data
+	}
+
+	logger.Error("Checks failed",
+		zap.Int("passed", passed),
+		zap.Int("required", required),
+		zap.String("signal_id", cfg.ID))
+	reason = fmt.Sprintf("%d of %d checks passed, %d required: %s", passed, len(results), required, strings.Join(failures, "; "))
+	return signal.StatusFailure, reason, data
+}
+
+// target caches the instance ID and region signals are published for so
+// they are only looked up once
+type target struct {
+	resolved   bool
+	instanceID string
+	region     string
+}
+
+// resolve uses the provided instance ID and region, falling back to IMDS
+// and then the AWS config for the region
+func (t *target) resolve(ctx context.Context, cfg signal.Config, imdsClient signal.IMDSClient, logger signal.Logger) error {
+	if t.resolved {
+		return nil
+	}
+
 	// Get instance ID - use provided value or fetch from IMDS
-	var instanceID string
 	if cfg.InstanceID != "" {
-		instanceID = cfg.InstanceID
-		logger.Debug("Using provided instance ID", zap.String("instance_id", instanceID))
+		t.instanceID = cfg.InstanceID
+		logger.Debug("Using provided instance ID", zap.String("instance_id", t.instanceID))
 	} else {
-		var err error
-		instanceID, err = imdsClient.GetInstanceID(ctx)
+		instanceID, err := imdsClient.GetInstanceID(ctx)
 		if err != nil {
-			return result, fmt.Errorf("failed to get instance ID: %w", err)
+			return fmt.Errorf("failed to get instance ID: %w", err)
 		}
-		logger.Debug("Fetched instance ID from IMDS", zap.String("instance_id", instanceID))
+		t.instanceID = instanceID
+		logger.Debug("Fetched instance ID from IMDS", zap.String("instance_id", t.instanceID))
 	}
 
 	// Resolve region - use provided value, fallback to IMDS, then AWS config
-	var region string
 	if cfg.Region != "" {
-		region = cfg.Region
-		logger.Debug("Using provided region", zap.String("region", region))
+		t.region = cfg.Region
+		logger.Debug("Using provided region", zap.String("region", t.region))
 	} else {
 		// Try to get region from IMDS first
-		var err error
-		region, err = imdsClient.GetRegion(ctx)
+		region, err := imdsClient.GetRegion(ctx)
 		if err != nil {
 			logger.Debug("Failed to get region from IMDS, falling back to AWS config", zap.Error(err))
 			// Region will be empty, let AWS SDK handle default resolution
 		} else {
-			logger.Debug("Fetched region from IMDS", zap.String("region", region))
+			t.region = region
+			logger.Debug("Fetched region from IMDS", zap.String("region", t.region))
 		}
 	}
 
-	// Publish signal
-	publishInput := signal.PublishInput{
-		QueueURL:       cfg.QueueURL,
-		SignalID:       cfg.ID,
-		InstanceID:     instanceID,
-		Status:         status,
-		Region:         region,
-		PublishTimeout: cfg.PublishTimeout,
-		Retries:        cfg.Retries,
-	}
-
-	if err := publisher.Publish(ctx, publishInput); err != nil {
-		return result, fmt.Errorf("failed to publish signal: %w", err)
-	}
-
-	logger.Info("Successfully published signal",
-		zap.String("status", status),
-		zap.String("signal_id", cfg.ID),
-		zap.String("instance_id", instanceID))
-
-	return result, nil
+	t.resolved = true
+	return nil
 }
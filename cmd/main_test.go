@@ -3,8 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -904,3 +910,1860 @@ func TestRun_ProvidedRegionAndInstanceID(t *testing.T) {
 		t.Errorf("Expected status 'SUCCESS', got: %s", lastCall.Status)
 	}
 }
+
+// Test that assume-role options are passed through to the publisher
+func TestRun_AssumeRoleOptions(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/111111111111/shared-signals",
+		ID:             "test-signal-assume-role",
+		Status:         "SUCCESS",
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+		AWS: signal.AWSOptions{
+			RoleARN:         "arn:aws:iam::111111111111:role/signal-publisher",
+			ExternalID:      "workload-account",
+			RoleSessionName: "tcsignal-aws",
+			RoleDuration:    15 * time.Minute,
+		},
+	}
+
+	_, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil {
+		t.Fatal("Expected publisher call to be recorded")
+	}
+
+	if lastCall.AWS != cfg.AWS {
+		t.Errorf("Expected AWS options %+v, got: %+v", cfg.AWS, lastCall.AWS)
+	}
+}
+
+// Test that --dry-run=no-exec resolves metadata but skips the command
+func TestRun_DryRunNoExec(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetExitCode(1) // Would fail if it were run
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-dry-run",
+		Exec:           "./install-app.sh",
+		DryRun:         signal.DryRunNoExec,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error for dry run, got: %v", err)
+	}
+
+	if mockExecutor.CallCount() != 0 {
+		t.Errorf("Expected executor NOT to be called, got: %d calls", mockExecutor.CallCount())
+	}
+
+	if mockIMDS.CallCount() != 2 {
+		t.Errorf("Expected instance ID and region to be resolved, got: %d IMDS calls", mockIMDS.CallCount())
+	}
+
+	if result.Status != "SUCCESS" || result.ShouldExit {
+		t.Errorf("Expected would-be SUCCESS result, got: %+v", result)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil || lastCall.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS to be rendered, got: %+v", lastCall)
+	}
+}
+
+// Test that during a dry run the command writes to stderr, so stdout only
+// holds the message
+func TestRun_DryRunCommandOutput(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-dry-run",
+		Exec:           "./install-app.sh",
+		DryRun:         signal.DryRunOn,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, mockExecutor, signal.NewMockPublisher(), signal.NewMockIMDSClient(), createTestLogger()); err != nil {
+		t.Fatalf("Expected no error for dry run, got: %v", err)
+	}
+
+	specs := mockExecutor.GetSpecs()
+	if len(specs) != 1 || specs[0].Stdout != os.Stderr {
+		t.Errorf("Expected the command's stdout to go to stderr, got: %+v", specs)
+	}
+}
+
+// Test that a command timeout publishes TIMEOUT with the reason
+func TestRun_ExecTimeout(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetExitCode(-1)
+	mockExecutor.SetError(fmt.Errorf("%w after 5m0s", signal.ErrExecTimeout))
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-exec-timeout",
+		Exec:           "yum install -y big-package",
+		ExecTimeout:    5 * time.Minute,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error (should send TIMEOUT status), got: %v", err)
+	}
+
+	if result.Status != signal.StatusTimeout || !result.ShouldExit || result.ExitCode != 1 {
+		t.Errorf("Expected TIMEOUT with exit code 1, got: %+v", result)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil {
+		t.Fatal("Expected publisher call to be recorded")
+	}
+
+	if lastCall.Reason != "command timed out after 5m0s" {
+		t.Errorf("Expected timeout reason, got: %q", lastCall.Reason)
+	}
+}
+
+// Test that a non-zero exit code is reported as the failure reason
+func TestRun_ExecFailureReason(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetExitCode(2)
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-exit-reason",
+		Exec:           "../test/fixtures/fail.sh",
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil || lastCall.Reason != "command exited with code 2" {
+		t.Errorf("Expected exit code reason, got: %+v", lastCall)
+	}
+}
+
+// Test that a command killed by a signal reports the signal as the reason
+func TestRun_ExecTerminatedBySignal(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetExitCode(-1)
+	mockExecutor.SetSignal("SIGKILL")
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-killed",
+		Exec:           "./install-app.sh",
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" {
+		t.Errorf("Expected FAILURE, got: %s", result.Status)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil || lastCall.Reason != "command terminated by SIGKILL" {
+		t.Errorf("Expected signal reason, got: %+v", lastCall)
+	}
+}
+
+// Test that an argv command is passed to the executor without a shell
+func TestRun_ArgvExec(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-argv",
+		Args:           []string{"/opt/app/install", "--name", "${var.name}"},
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS, got: %s", result.Status)
+	}
+
+	specs := mockExecutor.GetSpecs()
+	if len(specs) != 1 {
+		t.Fatalf("Expected executor to be called once, got: %d", len(specs))
+	}
+
+	if specs[0].Command != "" || len(specs[0].Args) != 3 || specs[0].Args[2] != "${var.name}" {
+		t.Errorf("Expected argv to be passed verbatim, got: %+v", specs[0])
+	}
+}
+
+// Test that a forwarded termination signal publishes CANCELLED with the reason
+func TestRun_ExecInterrupted(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetExitCode(-1)
+	mockExecutor.SetSignal("SIGTERM")
+	mockExecutor.SetInterrupted("SIGTERM")
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-interrupted",
+		Exec:           "./install-app.sh",
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusCancelled || result.ExitCode != 1 {
+		t.Errorf("Expected CANCELLED with exit code 1, got: %+v", result)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil || lastCall.Status != signal.StatusCancelled || lastCall.Reason != "interrupted by SIGTERM" {
+		t.Errorf("Expected interrupted reason, got: %+v", lastCall)
+	}
+}
+
+// Test that configured exit codes map to SUCCESS or custom statuses
+func TestRun_ExitCodeMapping(t *testing.T) {
+	testCases := []struct {
+		name           string
+		exitCode       int
+		expectedStatus signal.Status
+		expectedExit   bool
+	}{
+		{"extra success code", 2, "SUCCESS", false},
+		{"mapped status", 4, "SKIPPED", false},
+		{"unmapped failure", 1, "FAILURE", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExecutor := signal.NewMockExecutor()
+			mockPublisher := signal.NewMockPublisher()
+			mockIMDS := signal.NewMockIMDSClient()
+
+			mockExecutor.SetExitCode(tc.exitCode)
+
+			cfg := signal.Config{
+				QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:               "test-signal-mapped",
+				Exec:             "./install-app.sh",
+				SuccessExitCodes: []int{0, 2},
+				StatusMap:        map[int]signal.Status{4: "SKIPPED"},
+				Retries:          3,
+				PublishTimeout:   10 * time.Second,
+				Timeout:          30 * time.Second,
+			}
+
+			result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if result.Status != tc.expectedStatus {
+				t.Errorf("Expected %s, got: %s", tc.expectedStatus, result.Status)
+			}
+
+			if result.ShouldExit != tc.expectedExit {
+				t.Errorf("Expected ShouldExit %v, got: %v", tc.expectedExit, result.ShouldExit)
+			}
+
+			lastCall := mockPublisher.GetLastCall()
+			if lastCall == nil || lastCall.Status != tc.expectedStatus {
+				t.Errorf("Expected published status %s, got: %+v", tc.expectedStatus, lastCall)
+			}
+		})
+	}
+}
+
+// Test that steps stop at the first failure and the aggregate signal names it
+func TestRun_StepsStopOnFailure(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetResultForCommand("./app.sh", 3, nil)
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" || !result.ShouldExit || result.ExitCode != 1 {
+		t.Errorf("Expected FAILURE with exit code 1, got: %+v", result)
+	}
+
+	calls := mockExecutor.GetCalls()
+	if len(calls) != 2 || calls[0] != "./packages.sh" || calls[1] != "./app.sh" {
+		t.Errorf("Expected only the first two steps to run, got: %v", calls)
+	}
+
+	if mockPublisher.CallCount() != 1 {
+		t.Fatalf("Expected one aggregate signal, got %d", mockPublisher.CallCount())
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall.SignalID != "test-signal-steps" || lastCall.Status != "FAILURE" {
+		t.Errorf("Expected aggregate FAILURE signal, got: %+v", lastCall)
+	}
+
+	if lastCall.Reason != `step "app" failed: command exited with code 3` {
+		t.Errorf("Expected failed step in reason, got: %s", lastCall.Reason)
+	}
+}
+
+// Test that the aggregate status is that of the failed step and later steps
+// are SKIPPED
+func TestRun_StepsTimeoutStatus(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+
+	mockExecutor.SetResultForCommand("./app.sh", -1, fmt.Errorf("%w after 1m0s", signal.ErrExecTimeout))
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		SignalPerStep:  true,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusTimeout || !result.ShouldExit || result.ExitCode != 1 {
+		t.Errorf("Expected TIMEOUT with exit code 1, got: %+v", result)
+	}
+
+	statuses := make(map[string]signal.Status)
+	for _, call := range mockPublisher.GetCalls() {
+		statuses[call.SignalID] = call.Status
+	}
+	expected := map[string]signal.Status{
+		"test-signal-steps/packages": signal.StatusSuccess,
+		"test-signal-steps/app":      signal.StatusTimeout,
+		"test-signal-steps/verify":   signal.StatusSkipped,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected step statuses %v, got: %v", expected, statuses)
+	}
+}
+
+// Test that --continue-on-failure runs every step and reports all failures
+func TestRun_StepsContinueOnFailure(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetResultForCommand("./packages.sh", 1, nil)
+	mockExecutor.SetResultForCommand("./verify --all", 2, nil)
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		ContinueOnFailure: true,
+		Retries:           3,
+		PublishTimeout:    10 * time.Second,
+		Timeout:           30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" {
+		t.Errorf("Expected FAILURE, got: %s", result.Status)
+	}
+
+	if mockExecutor.CallCount() != 3 {
+		t.Errorf("Expected all three steps to run, got %d", mockExecutor.CallCount())
+	}
+
+	expectedReason := `step "packages" failed: command exited with code 1; step "verify" failed: command exited with code 2`
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Reason != expectedReason {
+		t.Errorf("Expected reason %q, got: %+v", expectedReason, lastCall)
+	}
+}
+
+// Test that --signal-per-step publishes a signal for every step, including
+// steps skipped after a failure
+func TestRun_SignalPerStep(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetResultForCommand("./app.sh", 1, nil)
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		SignalPerStep:  true,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" {
+		t.Errorf("Expected FAILURE, got: %s", result.Status)
+	}
+
+	expected := []struct {
+		id     string
+		status signal.Status
+		reason string
+	}{
+		{"test-signal-steps/packages", "SUCCESS", ""},
+		{"test-signal-steps/app", "FAILURE", "command exited with code 1"},
+		{"test-signal-steps/verify", "SKIPPED", `skipped after step "app" failed`},
+	}
+
+	calls := mockPublisher.GetCalls()
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d signals, got %d", len(expected), len(calls))
+	}
+
+	for i, e := range expected {
+		if calls[i].SignalID != e.id || calls[i].Status != e.status || calls[i].Reason != e.reason {
+			t.Errorf("Signal %d: expected %+v, got: %+v", i, e, calls[i])
+		}
+	}
+}
+
+// Test that with --signal-per-step the result of readiness probes is
+// published in the aggregate signal after the step signals
+// publishCountingExecutor records how many signals had been published when
+// each command started
+type publishCountingExecutor struct {
+	signal.Executor
+	publisher *signal.MockPublisher
+	published map[string]int
+}
+
+func (e *publishCountingExecutor) Run(ctx context.Context, spec signal.ExecSpec) (signal.ExecResult, error) {
+	command := spec.Command
+	if command == "" {
+		command = spec.Args[0]
+	}
+	e.published[command] = e.publisher.CallCount()
+	return e.Executor.Run(ctx, spec)
+}
+
+func TestRun_SignalPerStepPublishesAsStepsFinish(t *testing.T) {
+	mockPublisher := signal.NewMockPublisher()
+	executor := &publishCountingExecutor{
+		Executor:  signal.NewMockExecutor(),
+		publisher: mockPublisher,
+		published: make(map[string]int),
+	}
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		SignalPerStep:  true,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	_, err := run(context.Background(), cfg, executor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Each step's signal is sent before the next step starts
+	for command, expected := range map[string]int{"./app.sh": 1, "./verify": 2} {
+		if executor.published[command] != expected {
+			t.Errorf("Expected %d signals before %s ran, got %d", expected, command, executor.published[command])
+		}
+	}
+	if mockPublisher.CallCount() != 3 {
+		t.Errorf("Expected 3 signals, got %d", mockPublisher.CallCount())
+	}
+}
+
+func TestRun_SignalPerStepWithProbes(t *testing.T) {
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		SignalPerStep:  true,
+		WaitFile:       filepath.Join(t.TempDir(), "app.ready"),
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    50 * time.Millisecond,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, signal.NewMockExecutor(), mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusTimeout || !result.ShouldExit {
+		t.Errorf("Expected TIMEOUT with exit, got: %+v", result)
+	}
+
+	calls := mockPublisher.GetCalls()
+	if len(calls) != 4 {
+		t.Fatalf("Expected three step signals and the aggregate signal, got %d", len(calls))
+	}
+	for _, call := range calls[:3] {
+		if call.Status != signal.StatusSuccess {
+			t.Errorf("Expected step %s to be SUCCESS, got: %s", call.SignalID, call.Status)
+		}
+	}
+	if last := calls[3]; last.SignalID != "test-signal-steps" || last.Status != signal.StatusTimeout {
+		t.Errorf("Expected aggregate TIMEOUT signal, got: %+v", last)
+	}
+}
+
+// Test that with --signal-per-step the result of checks is published in the
+// aggregate signal after the step signals
+func TestRun_SignalPerStepWithChecks(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetResultForCommand("agent status", 1, nil)
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		SignalPerStep: true,
+		Checks: []signal.Check{
+			{Name: "disk", Command: "mountpoint -q /data"},
+			{Name: "agent", Command: "agent status"},
+			{Name: "app", Command: "curl -fs localhost:8080/health"},
+		},
+		CheckConcurrency: 1,
+		CheckTimeout:     time.Second,
+		Retries:          3,
+		PublishTimeout:   10 * time.Second,
+		Timeout:          30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusFailure || !result.ShouldExit {
+		t.Errorf("Expected FAILURE with exit, got: %+v", result)
+	}
+
+	calls := mockPublisher.GetCalls()
+	if len(calls) != 4 {
+		t.Fatalf("Expected three step signals and the aggregate signal, got %d", len(calls))
+	}
+	last := calls[3]
+	if last.SignalID != "test-signal-steps" || last.Status != signal.StatusFailure {
+		t.Errorf("Expected aggregate FAILURE signal, got: %+v", last)
+	}
+	if _, ok := last.Data["checks"]; !ok {
+		t.Errorf("Expected check results in the aggregate signal, got: %v", last.Data)
+	}
+}
+
+// Test that heartbeats are published while the command runs, followed by
+// the final status
+func TestRun_Heartbeat(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetOutput("restoring database\nrestored 40%\n")
+	mockExecutor.SetDelay(100 * time.Millisecond)
+
+	cfg := signal.Config{
+		QueueURL:        "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:              "test-signal-heartbeat",
+		Exec:            "./restore.sh",
+		Heartbeat:       20 * time.Millisecond,
+		HeartbeatOutput: true,
+		Retries:         3,
+		PublishTimeout:  10 * time.Second,
+		Timeout:         30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS, got: %s", result.Status)
+	}
+
+	calls := mockPublisher.GetCalls()
+	if len(calls) < 2 {
+		t.Fatalf("Expected at least one heartbeat and the final signal, got %d calls", len(calls))
+	}
+
+	for _, call := range calls[:len(calls)-1] {
+		if call.SignalID != "test-signal-heartbeat" || call.Status != "IN_PROGRESS" {
+			t.Errorf("Expected IN_PROGRESS heartbeat, got: %+v", call)
+		}
+		if call.Reason != "restored 40%" {
+			t.Errorf("Expected last output line as reason, got: %q", call.Reason)
+		}
+		if call.InstanceID != "i-1234567890abcdef0" {
+			t.Errorf("Expected heartbeat to carry instance ID, got: %s", call.InstanceID)
+		}
+	}
+
+	if final := calls[len(calls)-1]; final.Status != "SUCCESS" {
+		t.Errorf("Expected final SUCCESS signal, got: %+v", final)
+	}
+
+	// Instance metadata is only fetched once for all signals
+	if mockIMDS.CallCount() != 2 {
+		t.Errorf("Expected instance ID and region to be fetched once, got %d IMDS calls", mockIMDS.CallCount())
+	}
+}
+
+// Test that heartbeats are not published when --heartbeat is not set
+func TestRun_NoHeartbeat(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetDelay(50 * time.Millisecond)
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-no-heartbeat",
+		Exec:           "./restore.sh",
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if mockPublisher.CallCount() != 1 {
+		t.Errorf("Expected only the final signal, got %d calls", mockPublisher.CallCount())
+	}
+}
+
+// Test that command output is written to --exec-log-file
+func TestRun_ExecLogFile(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	mockExecutor.SetOutput("installing packages\ndone\n")
+
+	logFile := filepath.Join(t.TempDir(), "exec.log")
+	cfg := signal.Config{
+		QueueURL:          "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:                "test-signal-log",
+		Exec:              "./install.sh",
+		ExecLogFile:       logFile,
+		ExecLogMaxSize:    10,
+		ExecLogMaxBackups: 3,
+		ExecLogLines:      true,
+		Retries:           3,
+		PublishTimeout:    10 * time.Second,
+		Timeout:           30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Expected log file to exist, got: %v", err)
+	}
+
+	if string(data) != "installing packages\ndone\n" {
+		t.Errorf("Expected command output in log file, got: %q", data)
+	}
+}
+
+// Test that an unwritable --exec-log-file does not stop the command or signal
+func TestRun_ExecLogFileError(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-log",
+		Exec:           "./install.sh",
+		ExecLogFile:    filepath.Join(t.TempDir(), "missing", "exec.log"),
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "SUCCESS" || mockExecutor.CallCount() != 1 || mockPublisher.CallCount() != 1 {
+		t.Errorf("Expected command to run and signal SUCCESS, got: %+v", result)
+	}
+}
+
+// Test that a readiness probe alone determines the signalled status
+func TestRun_WaitProbe(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "app.ready")
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-probe",
+		WaitFile:       readyFile,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    100 * time.Millisecond,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	// Not ready within the wait timeout
+	mockPublisher := signal.NewMockPublisher()
+	result, err := run(context.Background(), cfg, signal.NewMockExecutor(), mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusTimeout || !result.ShouldExit {
+		t.Errorf("Expected TIMEOUT with exit, got: %+v", result)
+	}
+
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || !strings.Contains(lastCall.Reason, "not ready after 100ms") {
+		t.Errorf("Expected probe timeout reason, got: %+v", lastCall)
+	}
+
+	// Ready
+	if err := os.WriteFile(readyFile, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write ready file: %v", err)
+	}
+
+	mockPublisher = signal.NewMockPublisher()
+	result, err = run(context.Background(), cfg, signal.NewMockExecutor(), mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "SUCCESS" || mockPublisher.GetLastCall().Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS, got: %+v", result)
+	}
+}
+
+// Test that probes run after a successful command and are skipped after a
+// failed one
+func TestRun_ExecThenWaitProbe(t *testing.T) {
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-probe",
+		Exec:           "./install.sh",
+		WaitCmd:        "systemctl is-active app",
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    time.Second,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	result, err := run(context.Background(), cfg, mockExecutor, signal.NewMockPublisher(), signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	calls := mockExecutor.GetCalls()
+	if result.Status != "SUCCESS" || len(calls) != 2 || calls[1] != "systemctl is-active app" {
+		t.Errorf("Expected the command then the probe to run, got status %s and calls %v", result.Status, calls)
+	}
+
+	mockExecutor = signal.NewMockExecutor()
+	mockExecutor.SetExitCode(1)
+	result, err = run(context.Background(), cfg, mockExecutor, signal.NewMockPublisher(), signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" || mockExecutor.CallCount() != 1 {
+		t.Errorf("Expected FAILURE without probing, got status %s and %d calls", result.Status, mockExecutor.CallCount())
+	}
+}
+
+// Test that --wait-cloud-init signals cloud-init's result
+func TestRun_WaitCloudInit(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"var/lib/cloud/instance/boot-finished": "",
+		"run/cloud-init/result.json":           `{"v1": {"errors": ["('scripts_user', RuntimeError('Runparts: 1 failures'))"]}}`,
+		"run/cloud-init/status.json":           `{"v1": {"modules-final": {"errors": ["('scripts_user', RuntimeError('Runparts: 1 failures'))"]}}}`,
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockPublisher := signal.NewMockPublisher()
+	mockIMDS := signal.NewMockIMDSClient()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-cloud-init",
+		WaitCloudInit:  true,
+		CloudInitRoot:  root,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    time.Second,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, mockIMDS, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" || !result.ShouldExit {
+		t.Errorf("Expected FAILURE with exit, got: %+v", result)
+	}
+
+	expectedReason := "cloud-init failed: modules-final: ('scripts_user', RuntimeError('Runparts: 1 failures'))"
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Reason != expectedReason {
+		t.Errorf("Expected reason %q, got: %+v", expectedReason, lastCall)
+	}
+
+	if mockExecutor.CallCount() != 0 {
+		t.Errorf("Expected no command to run, got %d calls", mockExecutor.CallCount())
+	}
+}
+
+// Test that --wait-cloud-init signals TIMEOUT when cloud-init does not finish
+func TestRun_WaitCloudInitTimeout(t *testing.T) {
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-cloud-init",
+		WaitCloudInit:  true,
+		CloudInitRoot:  t.TempDir(),
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    50 * time.Millisecond,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, signal.NewMockExecutor(), mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusTimeout || !result.ShouldExit {
+		t.Errorf("Expected TIMEOUT with exit, got: %+v", result)
+	}
+
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Reason != "cloud-init did not finish within 50ms" {
+		t.Errorf("Expected timeout reason, got: %+v", lastCall)
+	}
+}
+
+// Test that supervise signals SUCCESS once ready and then exits with the
+// service's exit code
+func TestRun_SuperviseReady(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "server.ready")
+	if err := os.WriteFile(readyFile, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write ready file: %v", err)
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetDelay(200 * time.Millisecond)
+	mockExecutor.SetExitCode(3)
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-supervise",
+		Exec:           "./server",
+		Supervise:      true,
+		WaitFile:       readyFile,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    time.Second,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "SUCCESS" || !result.ShouldExit || result.ExitCode != 3 {
+		t.Errorf("Expected SUCCESS then exit code 3, got: %+v", result)
+	}
+
+	if mockPublisher.CallCount() != 1 || mockPublisher.GetLastCall().Status != "SUCCESS" {
+		t.Errorf("Expected a single SUCCESS signal, got: %+v", mockPublisher.GetCalls())
+	}
+
+	if specs := mockExecutor.GetSpecs(); len(specs) != 1 || specs[0].Stdin == nil {
+		t.Errorf("Expected the service to run once with stdin, got: %+v", specs)
+	}
+}
+
+// Test that supervise signals FAILURE when the service exits before it is
+// ready
+func TestRun_SuperviseExitsBeforeReady(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "server.ready")
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetExitCode(1)
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-supervise",
+		Exec:           "./server",
+		Supervise:      true,
+		WaitFile:       readyFile,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    time.Second,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" || result.ExitCode != 1 {
+		t.Errorf("Expected FAILURE with exit code 1, got: %+v", result)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil || lastCall.Reason != "service exited before becoming ready: command exited with code 1" {
+		t.Errorf("Expected exit reason, got: %+v", lastCall)
+	}
+}
+
+// Test that supervise signals TIMEOUT when probes time out but keeps the
+// service running
+func TestRun_SuperviseNotReady(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "server.ready")
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-supervise",
+		Exec:           "./server",
+		Supervise:      true,
+		WaitFile:       readyFile,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    50 * time.Millisecond,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetDelay(300 * time.Millisecond)
+	mockPublisher := signal.NewMockPublisher()
+
+	start := time.Now()
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusTimeout || result.ShouldExit || result.ExitCode != 0 {
+		t.Errorf("Expected TIMEOUT and the service's exit code 0, got: %+v", result)
+	}
+
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected supervise to wait for the service, returned after %s", elapsed)
+	}
+
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || !strings.Contains(lastCall.Reason, "not ready after 50ms") {
+		t.Errorf("Expected probe timeout reason, got: %+v", lastCall)
+	}
+}
+
+// Test that a failed publish does not stop supervision
+func TestRun_SupervisePublishError(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "server.ready")
+	if err := os.WriteFile(readyFile, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write ready file: %v", err)
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetDelay(50 * time.Millisecond)
+	mockPublisher := signal.NewMockPublisher()
+	mockPublisher.SetError(fmt.Errorf("queue unavailable"))
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-supervise",
+		Exec:           "./server",
+		Supervise:      true,
+		WaitFile:       readyFile,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    time.Second,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ShouldExit || mockExecutor.CallCount() != 1 {
+		t.Errorf("Expected the service to run to completion, got: %+v", result)
+	}
+}
+
+// Test that a supervised service gets the TCSIGNAL_* environment and its
+// output is written to --exec-log-file
+func TestRun_SuperviseOutputAndEnv(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetOutput("listening on :8080\n")
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-supervise",
+		Exec:           "./server",
+		Supervise:      true,
+		WaitInterval:   10 * time.Millisecond,
+		WaitTimeout:    time.Second,
+		ExecLogFile:    filepath.Join(t.TempDir(), "server.log"),
+		ExecLogLines:   true,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, mockExecutor, signal.NewMockPublisher(), signal.NewMockIMDSClient(), createTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	specs := mockExecutor.GetSpecs()
+	if len(specs) != 1 {
+		t.Fatalf("Expected 1 run, got: %d", len(specs))
+	}
+	env := make(map[string]string)
+	for _, kv := range specs[0].Env {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	if env["TCSIGNAL_ID"] != "test-signal-supervise" || env["TCSIGNAL_INSTANCE_ID"] != "i-1234567890abcdef0" {
+		t.Errorf("Expected the TCSIGNAL_* environment, got: %v", env)
+	}
+
+	data, err := os.ReadFile(cfg.ExecLogFile)
+	if err != nil {
+		t.Fatalf("Expected log file to exist, got: %v", err)
+	}
+	if string(data) != "listening on :8080\n" {
+		t.Errorf("Expected service output in log file, got: %q", data)
+	}
+}
+
+// Test that output patterns override or complement the exit code
+func TestRun_OutputPatterns(t *testing.T) {
+	testCases := []struct {
+		name             string
+		output           string
+		exitCode         int
+		successExitCodes []int
+		expectedStatus   signal.Status
+		expectedReason   string
+	}{
+		{"success pattern matched", "Installing\nInstallation complete\n", 0, nil, "SUCCESS", ""},
+		{"success pattern missing", "Installing\n", 0, nil, "FAILURE", "output did not match --success-pattern"},
+		{"failure pattern overrides exit code", "Installation complete\nERROR: license invalid\n", 0, nil, "FAILURE", "output matched --failure-pattern: ERROR: license invalid"},
+		{"exit code still fails", "Installation complete\n", 2, nil, "FAILURE", "command exited with code 2"},
+		{"success pattern matched with success exit code", "Installation complete\n", 2, []int{0, 2}, "SUCCESS", "command exited with code 2"},
+		{"success pattern missing with success exit code", "nothing\n", 2, []int{0, 2}, "FAILURE", "output did not match --success-pattern"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExecutor := signal.NewMockExecutor()
+			mockExecutor.SetOutput(tc.output)
+			mockExecutor.SetExitCode(tc.exitCode)
+			mockPublisher := signal.NewMockPublisher()
+
+			cfg := signal.Config{
+				QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:               "test-signal-patterns",
+				Exec:             "./vendor-installer.sh",
+				SuccessPattern:   regexp.MustCompile(`^Installation complete`),
+				FailurePattern:   regexp.MustCompile(`^ERROR:`),
+				SuccessExitCodes: tc.successExitCodes,
+				Retries:          3,
+				PublishTimeout:   10 * time.Second,
+				Timeout:          30 * time.Second,
+			}
+
+			result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if result.Status != tc.expectedStatus {
+				t.Errorf("Expected %s, got: %s", tc.expectedStatus, result.Status)
+			}
+
+			if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Reason != tc.expectedReason {
+				t.Errorf("Expected reason %q, got: %+v", tc.expectedReason, lastCall)
+			}
+		})
+	}
+}
+
+// Test that --kill-on-failure-pattern stops the command as soon as the
+// failure pattern matches
+func TestRun_KillOnFailurePattern(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetOutput("ERROR: mirror unreachable\n")
+	mockExecutor.SetDelay(10 * time.Second)
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-patterns",
+		Exec:           "./vendor-installer.sh",
+		FailurePattern: regexp.MustCompile(`^ERROR:`),
+		KillOnFailure:  true,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	start := time.Now()
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to be stopped early, took: %v", elapsed)
+	}
+
+	if result.Status != "FAILURE" || !result.ShouldExit {
+		t.Errorf("Expected FAILURE with exit, got: %+v", result)
+	}
+
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Reason != "output matched --failure-pattern: ERROR: mirror unreachable" {
+		t.Errorf("Expected failure pattern reason, got: %+v", lastCall)
+	}
+}
+
+// Test that a failed command is rerun and the attempts are signalled
+func TestRun_ExecRetries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		failures         int
+		expectedStatus   signal.Status
+		expectedAttempts int
+	}{
+		{"first attempt", 0, "SUCCESS", 1},
+		{"transient failure", 1, "SUCCESS", 2},
+		{"retries exhausted", 5, "FAILURE", 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExecutor := signal.NewMockExecutor()
+			mockExecutor.SetFailFirstNRuns(tc.failures, 1)
+			mockPublisher := signal.NewMockPublisher()
+
+			cfg := signal.Config{
+				QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:               "test-signal-retry",
+				Exec:             "./download-packages.sh",
+				ExecRetries:      2,
+				ExecRetryDelay:   10 * time.Millisecond,
+				ExecRetryBackoff: 2,
+				Retries:          3,
+				PublishTimeout:   10 * time.Second,
+				Timeout:          30 * time.Second,
+			}
+
+			result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if result.Status != tc.expectedStatus {
+				t.Errorf("Expected %s, got: %s", tc.expectedStatus, result.Status)
+			}
+
+			if mockExecutor.CallCount() != tc.expectedAttempts {
+				t.Errorf("Expected %d attempts, got: %d", tc.expectedAttempts, mockExecutor.CallCount())
+			}
+
+			lastCall := mockPublisher.GetLastCall()
+			if lastCall == nil || lastCall.Data["attempts"] != tc.expectedAttempts {
+				t.Errorf("Expected attempts %d in signal data, got: %+v", tc.expectedAttempts, lastCall)
+			}
+		})
+	}
+}
+
+// Test that failures which cannot pass on a rerun are not retried
+func TestRun_ExecRetriesNotRetryable(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetInterrupted("SIGTERM")
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:               "test-signal-retry",
+		Exec:             "./download-packages.sh",
+		ExecRetries:      2,
+		ExecRetryDelay:   10 * time.Millisecond,
+		ExecRetryBackoff: 2,
+		Retries:          3,
+		PublishTimeout:   10 * time.Second,
+		Timeout:          30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != signal.StatusCancelled || mockExecutor.CallCount() != 1 {
+		t.Errorf("Expected a single cancelled attempt, got: %+v after %d attempts", result, mockExecutor.CallCount())
+	}
+}
+
+// Test that --exec-timeout bounds all attempts together
+func TestRun_ExecRetriesBudget(t *testing.T) {
+	cfg := signal.Config{
+		QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:               "test-signal-retry",
+		Exec:             "./download-packages.sh",
+		ExecRetries:      10,
+		ExecRetryDelay:   100 * time.Millisecond,
+		ExecRetryBackoff: 2,
+		ExecTimeout:      150 * time.Millisecond,
+		Retries:          3,
+		PublishTimeout:   10 * time.Second,
+		Timeout:          30 * time.Second,
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetFailFirstNRuns(10, 1)
+	mockPublisher := signal.NewMockPublisher()
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Attempts at 0ms and 100ms; the 200ms retry is past the budget
+	if result.Status != "FAILURE" || mockExecutor.CallCount() != 2 {
+		t.Errorf("Expected FAILURE after 2 attempts, got: %+v after %d attempts", result, mockExecutor.CallCount())
+	}
+
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Data["attempts"] != 2 {
+		t.Errorf("Expected 2 attempts in signal data, got: %+v", lastCall)
+	}
+}
+
+// Test that the aggregate signal holds each step's attempts
+func TestRun_StepsExecRetries(t *testing.T) {
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-steps",
+		Steps: []signal.Step{
+			{Name: "packages", Run: "./packages.sh"},
+			{Name: "app", Run: "./app.sh"},
+			{Name: "verify", Args: []string{"./verify", "--all"}},
+		},
+		ExecRetries:      1,
+		ExecRetryDelay:   time.Millisecond,
+		ExecRetryBackoff: 1,
+		Retries:          3,
+		PublishTimeout:   10 * time.Second,
+		Timeout:          30 * time.Second,
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetFailFirstNRuns(1, 1)
+	mockPublisher := signal.NewMockPublisher()
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS, got: %s", result.Status)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil {
+		t.Fatal("Expected a signal to be published")
+	}
+
+	attempts := make(map[string]any)
+	steps, _ := lastCall.Data["steps"].(map[string]any)
+	for name, data := range steps {
+		attempts[name] = data.(map[string]any)["attempts"]
+	}
+	expected := map[string]any{"packages": 2, "app": 1, "verify": 1}
+	if !reflect.DeepEqual(attempts, expected) {
+		t.Errorf("Expected step attempts %v in signal data, got: %+v", expected, lastCall.Data)
+	}
+}
+
+// Test that the command is told which signal it runs for
+func TestRun_SignalEnvironment(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-env",
+		Exec:           "./install.sh",
+		ClearEnv:       true,
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, mockExecutor, signal.NewMockPublisher(), signal.NewMockIMDSClient(), createTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	specs := mockExecutor.GetSpecs()
+	if len(specs) != 1 {
+		t.Fatalf("Expected 1 run, got: %d", len(specs))
+	}
+
+	env := make(map[string]string)
+	for _, kv := range specs[0].Env {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+
+	expected := map[string]string{
+		"TCSIGNAL_ID":          "test-signal-env",
+		"TCSIGNAL_INSTANCE_ID": "i-1234567890abcdef0",
+		"TCSIGNAL_REGION":      "us-east-1",
+		"TCSIGNAL_QUEUE_URL":   cfg.QueueURL,
+	}
+	for k, v := range expected {
+		if env[k] != v {
+			t.Errorf("Expected %s=%s in the command's environment, got: %q", k, v, env[k])
+		}
+	}
+
+	// The data file is removed once the signal data has been read
+	if env["TCSIGNAL_DATA_FILE"] == "" {
+		t.Error("Expected TCSIGNAL_DATA_FILE in the command's environment")
+	} else if _, err := os.Stat(env["TCSIGNAL_DATA_FILE"]); !os.IsNotExist(err) {
+		t.Errorf("Expected data file to be removed, got: %v", err)
+	}
+}
+
+// Test that every attempt gets the --exec-stdin file and the TTY option
+func TestRun_ExecStdinAndTTY(t *testing.T) {
+	answers := filepath.Join(t.TempDir(), "answers.txt")
+	if err := os.WriteFile(answers, []byte("yes\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write answers file: %v", err)
+	}
+
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetFailFirstNRuns(1, 1)
+
+	cfg := signal.Config{
+		QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:               "test-signal-retry",
+		Exec:             "./download-packages.sh",
+		ExecRetries:      2,
+		ExecRetryDelay:   10 * time.Millisecond,
+		ExecRetryBackoff: 2,
+		ExecTTY:          true,
+		ExecStdin:        answers,
+		Retries:          3,
+		PublishTimeout:   10 * time.Second,
+		Timeout:          30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, signal.NewMockPublisher(), signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS, got: %s", result.Status)
+	}
+
+	specs := mockExecutor.GetSpecs()
+	if len(specs) != 2 {
+		t.Fatalf("Expected 2 runs, got: %d", len(specs))
+	}
+	for i, spec := range specs {
+		f, ok := spec.Stdin.(*os.File)
+		if !ok || f.Name() != answers {
+			t.Errorf("Run %d: expected stdin to be %s, got: %v", i+1, answers, spec.Stdin)
+		}
+		if !spec.TTY {
+			t.Errorf("Run %d: expected the command to run under a pseudo-terminal", i+1)
+		}
+	}
+}
+
+// Test that JSON the command writes to TCSIGNAL_DATA_FILE is sent as data
+func TestRun_SignalDataFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		exec     string
+		expected map[string]any
+	}{
+		{"object", `echo '{"version":"1.4.2","endpoint":"https://app.internal"}' > "$TCSIGNAL_DATA_FILE"`, map[string]any{"version": "1.4.2", "endpoint": "https://app.internal", "attempts": 1}},
+		{"nothing written", "true", map[string]any{"attempts": 1}},
+		{"invalid JSON", `echo 'version=1.4.2' > "$TCSIGNAL_DATA_FILE"`, map[string]any{"attempts": 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPublisher := signal.NewMockPublisher()
+
+			cfg := signal.Config{
+				QueueURL:         "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:               "test-signal-data",
+				Exec:             tc.exec,
+				ExecRetries:      1,
+				ExecRetryBackoff: 1,
+				Retries:          3,
+				PublishTimeout:   10 * time.Second,
+				Timeout:          30 * time.Second,
+			}
+
+			result, err := run(context.Background(), cfg, signal.NewDefaultExecutor(createTestLogger()), mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if result.Status != "SUCCESS" {
+				t.Errorf("Expected SUCCESS, got: %s", result.Status)
+			}
+
+			lastCall := mockPublisher.GetLastCall()
+			if lastCall == nil {
+				t.Fatal("Expected a signal to be published")
+			}
+
+			// How the process finished is covered by TestRun_ProcessData
+			data := maps.Clone(lastCall.Data)
+			delete(data, "process")
+			if !reflect.DeepEqual(data, tc.expected) {
+				t.Errorf("Expected data %v, got: %v", tc.expected, lastCall.Data)
+			}
+		})
+	}
+}
+
+// Test that --exec-timeout bounds the command but not probes and checks
+func TestRun_ExecTimeoutOnlyBoundsCommand(t *testing.T) {
+	testCases := []struct {
+		name           string
+		exec           string
+		expectedStatus signal.Status
+	}{
+		{"command", "sleep 5", "TIMEOUT"},
+		{"probes and checks", "true", "SUCCESS"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPublisher := signal.NewMockPublisher()
+
+			cfg := signal.Config{
+				QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:             "test-signal-exec-timeout",
+				Exec:           tc.exec,
+				ExecTimeout:    200 * time.Millisecond,
+				WaitCmd:        "sleep 0.4",
+				WaitInterval:   10 * time.Millisecond,
+				Checks:         []signal.Check{{Name: "slow", Command: "sleep 0.4"}},
+				CheckTimeout:   5 * time.Second,
+				Retries:        3,
+				PublishTimeout: 10 * time.Second,
+				Timeout:        30 * time.Second,
+			}
+
+			executor := signal.NewDefaultExecutor(createTestLogger())
+			executor.KillGrace = 100 * time.Millisecond
+			result, err := run(context.Background(), cfg, executor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if result.Status != tc.expectedStatus {
+				lastCall := mockPublisher.GetLastCall()
+				t.Errorf("Expected %s, got: %s (%+v)", tc.expectedStatus, result.Status, lastCall)
+			}
+		})
+	}
+}
+
+// Test that how the command finished and the resources it used are logged
+// and signalled
+func TestRun_ProcessData(t *testing.T) {
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-process",
+		Exec:           "kill -SEGV $$",
+		ExecDir:        t.TempDir(), // for any core file
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, signal.NewDefaultExecutor(createTestLogger()), mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" {
+		t.Errorf("Expected FAILURE, got: %s", result.Status)
+	}
+
+	lastCall := mockPublisher.GetLastCall()
+	if lastCall == nil {
+		t.Fatal("Expected a signal to be published")
+	}
+
+	// Whether a core is dumped depends on the host's core limit
+	if !strings.HasPrefix(lastCall.Reason, "command terminated by SIGSEGV") {
+		t.Errorf("Expected SIGSEGV reason, got: %q", lastCall.Reason)
+	}
+
+	process, ok := lastCall.Data["process"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected process data, got: %v", lastCall.Data)
+	}
+	if process["exit_code"] != -1 || process["signal"] != "SIGSEGV" {
+		t.Errorf("Expected exit code -1 and SIGSEGV, got: %v", process)
+	}
+	for _, key := range []string{"core_dumped", "wall_time_seconds", "user_cpu_seconds", "system_cpu_seconds", "max_rss_bytes"} {
+		if _, ok := process[key]; !ok {
+			t.Errorf("Expected %s in process data, got: %v", key, process)
+		}
+	}
+}
+
+// Test that a command that could not be started has no process data
+func TestRun_ProcessDataNotStarted(t *testing.T) {
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:             "test-signal-process",
+		Args:           []string{"/nonexistent/installer"},
+		Retries:        3,
+		PublishTimeout: 10 * time.Second,
+		Timeout:        30 * time.Second,
+	}
+
+	if _, err := run(context.Background(), cfg, signal.NewDefaultExecutor(createTestLogger()), mockPublisher, signal.NewMockIMDSClient(), createTestLogger()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if lastCall := mockPublisher.GetLastCall(); lastCall == nil || lastCall.Data != nil {
+		t.Errorf("Expected no data for a command that did not start, got: %+v", lastCall)
+	}
+}
+
+// Test that checks run and the signal holds each check's result
+func TestRun_Checks(t *testing.T) {
+	testCases := []struct {
+		name           string
+		minPass        int
+		expectedStatus signal.Status
+	}{
+		{"all required", 0, "FAILURE"},
+		{"min pass met", 2, "SUCCESS"},
+		{"min pass not met", 3, "FAILURE"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExecutor := signal.NewMockExecutor()
+			mockExecutor.SetResultForCommand("agent status", 1, nil)
+			mockPublisher := signal.NewMockPublisher()
+
+			cfg := signal.Config{
+				QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:       "test-signal-checks",
+				Checks: []signal.Check{
+					{Name: "disk", Command: "mountpoint -q /data"},
+					{Name: "agent", Command: "agent status"},
+					{Name: "app", Command: "curl -fs localhost:8080/health"},
+				},
+				CheckConcurrency: 3,
+				CheckTimeout:     time.Second,
+				MinPass:          tc.minPass,
+				Retries:          3,
+				PublishTimeout:   10 * time.Second,
+				Timeout:          30 * time.Second,
+			}
+
+			result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if result.Status != tc.expectedStatus {
+				t.Errorf("Expected %s, got: %s", tc.expectedStatus, result.Status)
+			}
+
+			if mockExecutor.CallCount() != 3 {
+				t.Errorf("Expected every check to run, got: %d", mockExecutor.CallCount())
+			}
+
+			lastCall := mockPublisher.GetLastCall()
+			if lastCall == nil {
+				t.Fatal("Expected a signal to be published")
+			}
+
+			checks, _ := lastCall.Data["checks"].(map[string]any)
+			agent, _ := checks["agent"].(map[string]any)
+			disk, _ := checks["disk"].(map[string]any)
+			if agent["passed"] != false || agent["reason"] != "exited with code 1" || disk["passed"] != true {
+				t.Errorf("Expected per-check results, got: %v", lastCall.Data)
+			}
+			if _, ok := disk["duration_seconds"]; !ok {
+				t.Errorf("Expected check durations, got: %v", lastCall.Data)
+			}
+
+			if tc.expectedStatus == "FAILURE" && !strings.HasPrefix(lastCall.Reason, "2 of 3 checks passed") {
+				t.Errorf("Expected reason to count passed checks, got: %q", lastCall.Reason)
+			}
+		})
+	}
+}
+
+// Test that checks are skipped after the command fails
+func TestRun_ExecThenChecks(t *testing.T) {
+	mockExecutor := signal.NewMockExecutor()
+	mockExecutor.SetResultForCommand("./install.sh", 1, nil)
+	mockPublisher := signal.NewMockPublisher()
+
+	cfg := signal.Config{
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		ID:       "test-signal-checks",
+		Checks: []signal.Check{
+			{Name: "disk", Command: "mountpoint -q /data"},
+			{Name: "agent", Command: "agent status"},
+			{Name: "app", Command: "curl -fs localhost:8080/health"},
+		},
+		CheckConcurrency: 3,
+		CheckTimeout:     time.Second,
+		Exec:             "./install.sh",
+		Retries:          3,
+		PublishTimeout:   10 * time.Second,
+		Timeout:          30 * time.Second,
+	}
+
+	result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != "FAILURE" || mockExecutor.CallCount() != 1 {
+		t.Errorf("Expected FAILURE without running checks, got: %+v after %d runs", result, mockExecutor.CallCount())
+	}
+}
+
+// Test that a termination signal received while no command runs cancels
+// the run and is signalled as CANCELLED
+func TestRun_InterruptedOutsideCommand(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(*signal.Config, *signal.MockExecutor)
+	}{
+		{
+			name: "retry delay",
+			modify: func(cfg *signal.Config, executor *signal.MockExecutor) {
+				cfg.Exec = "./flaky.sh"
+				cfg.ExecRetries = 1
+				cfg.ExecRetryDelay = time.Minute
+				executor.SetExitCode(1)
+			},
+		},
+		{
+			name: "readiness probe",
+			modify: func(cfg *signal.Config, executor *signal.MockExecutor) {
+				cfg.WaitFile = filepath.Join(t.TempDir(), "never")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExecutor := signal.NewMockExecutor()
+			mockPublisher := signal.NewMockPublisher()
+
+			cfg := signal.Config{
+				QueueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				ID:             "test-signal-interrupt",
+				WaitInterval:   10 * time.Millisecond,
+				Retries:        3,
+				PublishTimeout: 10 * time.Second,
+				Timeout:        30 * time.Second,
+			}
+			tc.modify(&cfg, mockExecutor)
+
+			self, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.AfterFunc(100*time.Millisecond, func() {
+				if err := self.Signal(syscall.SIGTERM); err != nil {
+					t.Errorf("Cannot signal this process: %v", err)
+				}
+			})
+
+			start := time.Now()
+			result, err := run(context.Background(), cfg, mockExecutor, mockPublisher, signal.NewMockIMDSClient(), createTestLogger())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected the run to stop when interrupted, took %s", elapsed)
+			}
+
+			if result.Status != "CANCELLED" || !result.ShouldExit || result.ExitCode != 1 {
+				t.Errorf("Expected CANCELLED with exit code 1, got: %+v", result)
+			}
+			lastCall := mockPublisher.GetLastCall()
+			if lastCall == nil || lastCall.Status != "CANCELLED" || lastCall.Reason != "interrupted by SIGTERM" {
+				t.Errorf("Expected CANCELLED interrupted by SIGTERM to be published, got: %+v", lastCall)
+			}
+		})
+	}
+}
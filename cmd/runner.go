@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/terraconstructs/signal-aws"
+	"go.uber.org/zap"
+)
+
+// commandRunner runs commands with the configured heartbeat and output
+// capture and turns how they finished into signals
+type commandRunner struct {
+	cfg       signal.Config
+	executor  signal.Executor
+	heartbeat *heartbeat
+	target    *target
+	logFile   io.Writer
+	logger    signal.Logger
+}
+
+// stepSignal is a signal to publish for a single step
+type stepSignal struct {
+	id     string
+	status signal.Status
+	reason string
+	data   map[string]any
+}
+
+// runSteps runs cfg.Steps in order and returns the aggregate status, reason
+// and data. Each step's signal is passed to stepDone as soon as the step
+// finishes. The aggregate status is that of the first step that failed, and
+// the aggregate data holds each step's data under "steps". Unless
+// --continue-on-failure is set, steps after a failure are not run and are
+// signalled as SKIPPED.
+func (r *commandRunner) runSteps(ctx context.Context, stepDone func(stepSignal)) (signal.Status, string, map[string]any) {
+	cfg, logger := r.cfg, r.logger
+
+	var (
+		status   = signal.StatusSuccess
+		reason   string
+		failed   signal.Status
+		failures []string
+		stepData = make(map[string]any)
+		stopped  string
+	)
+
+	for _, step := range cfg.Steps {
+		id := signal.StepSignalID(cfg.ID, step)
+		if stopped != "" {
+			logger.Warn("Skipping step",
+				zap.String("step", step.Name),
+				zap.String("failed_step", stopped),
+				zap.String("signal_id", cfg.ID))
+			stepDone(stepSignal{id: id, status: signal.StatusSkipped, reason: fmt.Sprintf("skipped after step %q failed", stopped)})
+			continue
+		}
+
+		var (
+			stepStatus  = signal.StatusSuccess
+			stepReason  string
+			data        map[string]any
+			interrupted bool
+		)
+		if cfg.DryRun == signal.DryRunNoExec {
+			logger.Info("Dry run: skipping step execution",
+				zap.String("step", step.Name),
+				zap.Stringer("command", cfg.StepExecSpec(step)),
+				zap.String("signal_id", cfg.ID))
+		} else {
+			logger.Info("Running step",
+				zap.String("step", step.Name),
+				zap.String("signal_id", cfg.ID))
+			// Heartbeats go to the signal the waiter is watching
+			heartbeatID := cfg.ID
+			if cfg.SignalPerStep {
+				heartbeatID = id
+			}
+			stepStatus, stepReason, data, interrupted = r.execute(ctx, cfg.StepExecSpec(step), heartbeatID)
+		}
+		stepDone(stepSignal{id: id, status: stepStatus, reason: stepReason, data: data})
+		if len(data) > 0 {
+			stepData[step.Name] = data
+		}
+
+		switch {
+		case stepStatus.Failed():
+			if failed == "" {
+				failed = stepStatus
+			}
+			failure := fmt.Sprintf("step %q failed", step.Name)
+			if stepReason != "" {
+				failure += ": " + stepReason
+			}
+			failures = append(failures, failure)
+
+			// An interrupt stops the run even with --continue-on-failure
+			if !cfg.ContinueOnFailure || interrupted {
+				stopped = step.Name
+			}
+		case stepStatus != signal.StatusSuccess && status == signal.StatusSuccess:
+			// Report the first non-SUCCESS status from --status-map
+			status = stepStatus
+			reason = fmt.Sprintf("step %q: %s", step.Name, stepReason)
+		}
+	}
+
+	if len(failures) > 0 {
+		status = failed
+		reason = strings.Join(failures, "; ")
+	}
+
+	var data map[string]any
+	if len(stepData) > 0 {
+		data = map[string]any{"steps": stepData}
+	}
+
+	return status, reason, data
+}
+
+// execute runs a command, rerunning it after a failure up to --exec-retries
+// times, and returns the status, reason and data to signal. --exec-timeout
+// bounds all attempts together; it does not apply to probes and checks,
+// which run with the same executor. interrupted reports whether a
+// termination signal was forwarded to the command.
+func (r *commandRunner) execute(ctx context.Context, spec signal.ExecSpec, signalID string) (status signal.Status, reason string, data map[string]any, interrupted bool) {
+	cfg, logger := r.cfg, r.logger
+
+	if cfg.ExecTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.ExecTimeout, fmt.Errorf("%w after %s", signal.ErrExecTimeout, cfg.ExecTimeout))
+		defer cancel()
+	}
+
+	// The data file is optional, so the command still runs without it
+	dataFile, err := signal.CreateDataFile(spec.User, spec.Group)
+	if err != nil {
+		logger.Warn("Failed to create signal data file", zap.Error(err))
+	} else {
+		defer dataFile.Remove()
+	}
+	r.setSignalEnv(&spec, signalID, dataFile)
+
+	delay := cfg.ExecRetryDelay
+	for attempt := 1; ; attempt++ {
+		if dataFile != nil && attempt > 1 {
+			if err := dataFile.Reset(); err != nil {
+				logger.Warn("Failed to reset signal data file", zap.Error(err))
+			}
+		}
+
+		result := r.attempt(ctx, spec, signalID, attempt)
+		status, reason, interrupted = result.status, result.reason, result.interrupted
+
+		if status != signal.StatusFailure || !result.retryable || attempt > cfg.ExecRetries {
+			return status, reason, r.signalData(dataFile, attempt, result.exec, signalID), interrupted
+		}
+
+		logger.Warn("Command failed, retrying",
+			zap.Stringer("command", spec),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", cfg.ExecRetries+1),
+			zap.String("reason", reason),
+			zap.Duration("delay", delay),
+			zap.String("signal_id", signalID))
+
+		select {
+		case <-ctx.Done():
+			if cancelled, why, ok := interruption(ctx); ok {
+				logger.Error("Interrupted before retrying command",
+					zap.Stringer("command", spec),
+					zap.String("reason", why),
+					zap.String("signal_id", signalID))
+				return cancelled, why, r.signalData(dataFile, attempt, result.exec, signalID), true
+			}
+			logger.Error("No time left to retry command",
+				zap.Stringer("command", spec),
+				zap.NamedError("cause", context.Cause(ctx)),
+				zap.String("signal_id", signalID))
+			return status, reason, r.signalData(dataFile, attempt, result.exec, signalID), interrupted
+		case <-time.After(delay):
+		}
+		delay = time.Duration(float64(delay) * max(cfg.ExecRetryBackoff, 1))
+	}
+}
+
+// setSignalEnv tells the command which signal it runs for and where it can
+// write data for the signal
+func (r *commandRunner) setSignalEnv(spec *signal.ExecSpec, signalID string, dataFile *signal.DataFile) {
+	if spec.Env == nil {
+		spec.Env = os.Environ()
+	}
+	spec.Env = append(spec.Env,
+		"TCSIGNAL_ID="+signalID,
+		"TCSIGNAL_INSTANCE_ID="+r.target.instanceID,
+		"TCSIGNAL_REGION="+r.target.region,
+		"TCSIGNAL_QUEUE_URL="+r.cfg.QueueURL)
+	if dataFile != nil {
+		spec.Env = append(spec.Env, "TCSIGNAL_DATA_FILE="+dataFile.Path)
+	}
+}
+
+// signalData returns the data the command wrote to its data file along with
+// how the last attempt finished and the number of attempts when retrying.
+// Invalid or oversized data is logged and dropped rather than failing the
+// signal.
+func (r *commandRunner) signalData(dataFile *signal.DataFile, attempts int, result signal.ExecResult, signalID string) map[string]any {
+	var data map[string]any
+	if dataFile != nil {
+		var err error
+		if data, err = dataFile.Read(); err != nil {
+			r.logger.Warn("Ignoring invalid signal data", zap.Error(err), zap.String("signal_id", signalID))
+		}
+	}
+	if data == nil {
+		data = make(map[string]any)
+	}
+
+	if result.Started {
+		data["process"] = processData(result)
+	}
+	if r.cfg.ExecRetries > 0 {
+		data["attempts"] = attempts
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// processData describes how a command finished and the resources it used
+func processData(result signal.ExecResult) map[string]any {
+	data := map[string]any{
+		"exit_code":          result.ExitCode,
+		"wall_time_seconds":  seconds(result.Duration),
+		"user_cpu_seconds":   seconds(result.Usage.UserTime),
+		"system_cpu_seconds": seconds(result.Usage.SystemTime),
+	}
+	if result.Signal != "" {
+		data["signal"] = result.Signal
+		data["core_dumped"] = result.CoreDumped
+	}
+	if result.Usage.MaxRSS > 0 {
+		data["max_rss_bytes"] = result.Usage.MaxRSS
+	}
+	return data
+}
+
+// seconds converts d to seconds with millisecond precision
+func seconds(d time.Duration) float64 {
+	return float64(d.Milliseconds()) / 1000
+}
+
+// attemptResult is how a single run of a command went
+type attemptResult struct {
+	exec        signal.ExecResult
+	status      signal.Status
+	reason      string
+	interrupted bool
+	// retryable reports whether a failure may pass on a rerun
+	retryable bool
+}
+
+// attempt runs a command once, publishing heartbeats for signalID while it
+// runs, and determines the status and reason to signal from how it
+// finished. Timeouts, interrupts and commands that could not be started are
+// not retryable.
+func (r *commandRunner) attempt(ctx context.Context, spec signal.ExecSpec, signalID string, attempt int) attemptResult {
+	cfg, logger := r.cfg, r.logger
+
+	var (
+		status                 signal.Status
+		reason                 string
+		interrupted, retryable bool
+	)
+
+	// A file given as stdin is reopened so every attempt reads all of it
+	stdin, closeStdin, err := cfg.OpenExecStdin()
+	if err != nil {
+		logger.Error("Command execution failed",
+			zap.Stringer("command", spec),
+			zap.Error(err),
+			zap.String("signal_id", signalID))
+		return attemptResult{
+			exec:   signal.ExecResult{ExitCode: -1},
+			status: signal.StatusFailure,
+			reason: fmt.Sprintf("command execution failed: %v", err),
+		}
+	}
+	defer closeStdin()
+	spec.Stdin = stdin
+
+	finishOutput := r.captureOutput(&spec, signalID)
+	runCtx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+	matcher := r.matchOutput(&spec, cancelRun)
+	stopHeartbeat := r.heartbeat.start(ctx, signalID, &spec)
+	execResult, err := r.executor.Run(runCtx, spec)
+	stopHeartbeat()
+	finishOutput()
+	if matcher != nil {
+		matcher.Flush()
+	}
+
+	failureLine, failureMatched := "", false
+	if matcher != nil {
+		failureLine, failureMatched = matcher.FailureLine()
+	}
+
+	var interruptErr *interruptedError
+	if errors.As(err, &interruptErr) {
+		// The run was interrupted before the command started
+		logger.Error("Command interrupted",
+			zap.Stringer("command", spec),
+			zap.String("signal", interruptErr.signal),
+			zap.String("signal_id", signalID))
+		status = signal.StatusCancelled
+		reason = interruptErr.Error()
+		interrupted = true
+	} else if errors.Is(err, signal.ErrExecTimeout) {
+		logger.Error("Command timed out",
+			zap.Stringer("command", spec),
+			zap.Duration("exec_timeout", cfg.ExecTimeout),
+			zap.String("signal_id", signalID))
+		status = signal.StatusTimeout
+		reason = err.Error()
+	} else if err != nil && !failureMatched {
+		logger.Error("Command execution failed",
+			zap.Stringer("command", spec),
+			zap.Error(err),
+			zap.String("signal_id", signalID))
+		status = signal.StatusFailure
+		reason = fmt.Sprintf("command execution failed: %v", err)
+	} else if execResult.Interrupted != "" {
+		logger.Error("Command interrupted",
+			zap.Stringer("command", spec),
+			zap.String("signal", execResult.Interrupted),
+			zap.String("signal_id", signalID))
+		status = signal.StatusCancelled
+		reason = fmt.Sprintf("interrupted by %s", execResult.Interrupted)
+		interrupted = true
+	} else if failureMatched {
+		retryable = true
+		// The pattern overrides the exit code, including the termination
+		// caused by --kill-on-failure-pattern
+		logger.Error("Command output matched failure pattern",
+			zap.Stringer("command", spec),
+			zap.String("line", failureLine),
+			zap.String("signal_id", signalID))
+		status = signal.StatusFailure
+		reason = "output matched --failure-pattern: " + failureLine
+	} else if execResult.Signal != "" {
+		status = signal.StatusFailure
+		reason = describeExit(execResult, nil)
+		retryable = true
+	} else {
+		retryable = true
+		status = cfg.ExitStatus(execResult.ExitCode)
+		if execResult.ExitCode != 0 {
+			reason = fmt.Sprintf("command exited with code %d", execResult.ExitCode)
+		}
+		if status == signal.StatusSuccess && matcher != nil && cfg.SuccessPattern != nil && !matcher.SuccessMatched() {
+			// A success pattern is required in addition to a successful
+			// exit, whichever --success-exit-codes it exited with
+			logger.Error("Command output did not match success pattern",
+				zap.Stringer("command", spec),
+				zap.String("signal_id", signalID))
+			status = signal.StatusFailure
+			reason = "output did not match --success-pattern"
+		}
+	}
+
+	logger.Info("Command finished",
+		zap.Stringer("command", spec),
+		zap.Int("attempt", attempt),
+		zap.Int("exit_code", execResult.ExitCode),
+		zap.String("signal", execResult.Signal),
+		zap.Bool("core_dumped", execResult.CoreDumped),
+		zap.Duration("duration", execResult.Duration),
+		zap.Int64("max_rss", execResult.Usage.MaxRSS),
+		zap.Duration("user_time", execResult.Usage.UserTime),
+		zap.Duration("system_time", execResult.Usage.SystemTime),
+		zap.String("signal_id", signalID))
+
+	return attemptResult{
+		exec:        execResult,
+		status:      status,
+		reason:      reason,
+		interrupted: interrupted,
+		retryable:   retryable,
+	}
+}
+
+// captureOutput tees spec's output to --exec-log-file and, with
+// --exec-log-lines, logs each line. During a dry run the command's stdout
+// goes to stderr, leaving stdout to the message. The returned function
+// flushes any partial lines once the command has finished.
+func (r *commandRunner) captureOutput(spec *signal.ExecSpec, signalID string) (finish func()) {
+	if r.cfg.DryRun != signal.DryRunOff && spec.Stdout == nil {
+		spec.Stdout = os.Stderr
+	}
+
+	if r.logFile == nil && !r.cfg.ExecLogLines {
+		return func() {}
+	}
+
+	stdout := []io.Writer{writerOrDefault(spec.Stdout, os.Stdout)}
+	stderr := []io.Writer{writerOrDefault(spec.Stderr, os.Stderr)}
+
+	if r.logFile != nil {
+		logFile := &bestEffortWriter{w: r.logFile, logger: r.logger}
+		stdout = append(stdout, logFile)
+		stderr = append(stderr, logFile)
+	}
+
+	var lineWriters []*signal.LineLogWriter
+	if r.cfg.ExecLogLines {
+		for _, stream := range []string{"stdout", "stderr"} {
+			w := signal.NewLineLogWriter(r.logger,
+				zap.String("stream", stream),
+				zap.String("signal_id", signalID),
+				zap.String("instance_id", r.target.instanceID))
+			lineWriters = append(lineWriters, w)
+		}
+		stdout = append(stdout, lineWriters[0])
+		stderr = append(stderr, lineWriters[1])
+	}
+
+	spec.Stdout = io.MultiWriter(stdout...)
+	spec.Stderr = io.MultiWriter(stderr...)
+
+	return func() {
+		for _, w := range lineWriters {
+			w.Flush()
+		}
+	}
+}
+
+// errFailurePattern cancels a command terminated by --kill-on-failure-pattern
+var errFailurePattern = errors.New("output matched --failure-pattern")
+
+// matchOutput checks spec's output against --success-pattern and
+// --failure-pattern. With --kill-on-failure-pattern, cancel is called when
+// the failure pattern matches so the command is terminated. The matcher is
+// nil when no patterns are set.
+func (r *commandRunner) matchOutput(spec *signal.ExecSpec, cancel context.CancelCauseFunc) *signal.PatternMatcher {
+	if r.cfg.SuccessPattern == nil && r.cfg.FailurePattern == nil {
+		return nil
+	}
+
+	matcher := &signal.PatternMatcher{
+		Success: r.cfg.SuccessPattern,
+		Failure: r.cfg.FailurePattern,
+	}
+	if r.cfg.KillOnFailure {
+		matcher.OnFailure = func(line string) {
+			r.logger.Warn("Command output matched failure pattern, terminating command", zap.String("line", line))
+			cancel(errFailurePattern)
+		}
+	}
+
+	spec.Stdout = io.MultiWriter(writerOrDefault(spec.Stdout, os.Stdout), matcher.Writer())
+	spec.Stderr = io.MultiWriter(writerOrDefault(spec.Stderr, os.Stderr), matcher.Writer())
+
+	return matcher
+}
+
+// bestEffortWriter ignores write errors so a full disk or failed log
+// rotation cannot break the command's output pipes. The first error is
+// logged.
+type bestEffortWriter struct {
+	w      io.Writer
+	logger signal.Logger
+	once   sync.Once
+}
+
+func (b *bestEffortWriter) Write(p []byte) (int, error) {
+	if _, err := b.w.Write(p); err != nil {
+		b.once.Do(func() {
+			b.logger.Warn("Failed to write command output to log file", zap.Error(err))
+		})
+	}
+	return len(p), nil
+}
+
+func writerOrDefault(w, def io.Writer) io.Writer {
+	if w == nil {
+		return def
+	}
+	return w
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/terraconstructs/signal-aws"
+	"go.uber.org/zap"
+)
+
+// supervise starts a long-running service, signals SUCCESS once its
+// readiness probes pass or FAILURE if it exits or never becomes ready, and
+// then waits for it to exit. The result carries the service's exit code.
+func (r *commandRunner) supervise(ctx context.Context, publisher signal.Publisher, imdsClient signal.IMDSClient) (*RunResult, error) {
+	cfg, executor, logger := r.cfg, r.executor, r.logger
+
+	// The service has no data file: its signal is sent while it runs
+	spec := cfg.ExecSpec()
+	r.setSignalEnv(&spec, cfg.ID, nil)
+	finishOutput := r.captureOutput(&spec, cfg.ID)
+
+	logger.Info("Starting supervised service",
+		zap.Stringer("command", spec),
+		zap.String("signal_id", cfg.ID))
+
+	var (
+		execResult signal.ExecResult
+		execErr    error
+		exited     = make(chan struct{})
+	)
+	go func() {
+		defer close(exited)
+		defer finishOutput()
+		stdin, closeStdin, err := cfg.OpenExecStdin()
+		if err != nil {
+			execErr = err
+			return
+		}
+		defer closeStdin()
+		spec.Stdin = stdin
+		execResult, execErr = executor.Run(ctx, spec)
+	}()
+
+	// Stop probing as soon as the service exits
+	probeCtx, cancelProbes := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-exited:
+			cancelProbes()
+		case <-probeCtx.Done():
+		}
+	}()
+
+	var probeErr error
+	if probes := cfg.Probes(executor); len(probes) > 0 {
+		probeErr = signal.WaitReady(probeCtx, probes, cfg.WaitInterval, cfg.WaitTimeout, logger)
+	}
+	cancelProbes()
+
+	status, reason := signal.StatusSuccess, ""
+	select {
+	case <-exited:
+		status = signal.StatusFailure
+		reason = "service exited before becoming ready: " + describeExit(execResult, execErr)
+		logger.Error("Supervised service exited before becoming ready",
+			zap.String("reason", reason),
+			zap.String("signal_id", cfg.ID))
+	default:
+		if probeErr != nil {
+			status = signal.StatusFailure
+			if errors.Is(probeErr, signal.ErrProbeTimeout) {
+				status = signal.StatusTimeout
+			}
+			reason = probeErr.Error()
+			logger.Error("Supervised service did not become ready",
+				zap.Error(probeErr),
+				zap.String("signal_id", cfg.ID))
+		} else {
+			logger.Info("Supervised service is ready", zap.String("signal_id", cfg.ID))
+		}
+	}
+
+	// A failed publish must not take the service down with it
+	signals := []stepSignal{{id: cfg.ID, status: status, reason: reason}}
+	if err := publishSignals(ctx, cfg, publisher, imdsClient, r.target, logger, signals); err != nil {
+		logger.Error("Failed to publish signal, continuing to supervise", zap.Error(err))
+	}
+
+	<-exited
+	if execErr != nil {
+		logger.Error("Supervised service failed", zap.Error(execErr))
+	}
+
+	exitCode := execResult.ShellExitCode()
+	if execErr != nil {
+		exitCode = 1
+	}
+
+	logger.Info("Supervised service exited",
+		zap.Int("exit_code", exitCode),
+		zap.String("signal", execResult.Signal),
+		zap.Bool("core_dumped", execResult.CoreDumped),
+		zap.Duration("duration", execResult.Duration.Round(time.Millisecond)),
+		zap.Int64("max_rss", execResult.Usage.MaxRSS),
+		zap.String("signal_id", cfg.ID))
+
+	return &RunResult{
+		Status:     status,
+		ShouldExit: exitCode != 0,
+		ExitCode:   exitCode,
+	}, nil
+}
+
+// describeExit describes how a command finished for a signal reason
+func describeExit(result signal.ExecResult, err error) string {
+	switch {
+	case err != nil:
+		return fmt.Sprintf("command execution failed: %v", err)
+	case result.Signal != "" && result.CoreDumped:
+		return fmt.Sprintf("command terminated by %s (core dumped)", result.Signal)
+	case result.Signal != "":
+		return fmt.Sprintf("command terminated by %s", result.Signal)
+	default:
+		return fmt.Sprintf("command exited with code %d", result.ExitCode)
+	}
+}
@@ -3,66 +3,492 @@ package signal
 import (
 	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// DryRunMode controls whether a signal is actually sent
+type DryRunMode string
+
+const (
+	DryRunOff    DryRunMode = ""
+	DryRunOn     DryRunMode = "true"
+	DryRunNoExec DryRunMode = "no-exec"
+)
+
+// dryRunValue lets --dry-run be used as a boolean or as --dry-run=no-exec
+type dryRunValue DryRunMode
+
+func (v *dryRunValue) String() string {
+	return string(*v)
+}
+
+func (v *dryRunValue) Set(s string) error {
+	switch s {
+	case "true", "1":
+		*v = dryRunValue(DryRunOn)
+	case "false", "0":
+		*v = dryRunValue(DryRunOff)
+	case string(DryRunNoExec):
+		*v = dryRunValue(DryRunNoExec)
+	default:
+		return fmt.Errorf("must be true, false or no-exec")
+	}
+	return nil
+}
+
+func (v *dryRunValue) IsBoolFlag() bool {
+	return true
+}
+
+// execListValue collects repeated --exec flags
+type execListValue []string
+
+func (v *execListValue) String() string {
+	return strings.Join(*v, "; ")
+}
+
+func (v *execListValue) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+// checkListValue collects repeated --check NAME=CMD flags
+type checkListValue []Check
+
+func (v *checkListValue) String() string {
+	checks := make([]string, len(*v))
+	for i, check := range *v {
+		checks[i] = check.Name + "=" + check.Command
+	}
+	return strings.Join(checks, "; ")
+}
+
+func (v *checkListValue) Set(s string) error {
+	check, err := ParseCheck(s)
+	if err != nil {
+		return err
+	}
+	for _, existing := range *v {
+		if existing.Name == check.Name {
+			return fmt.Errorf("duplicate check name %q", check.Name)
+		}
+	}
+	*v = append(*v, check)
+	return nil
+}
+
+// statusPattern matches status names accepted by --status-map
+var statusPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// exitCodesValue parses a comma-separated list of exit codes
+type exitCodesValue []int
+
+func (v *exitCodesValue) String() string {
+	codes := make([]string, len(*v))
+	for i, code := range *v {
+		codes[i] = strconv.Itoa(code)
+	}
+	return strings.Join(codes, ",")
+}
+
+func (v *exitCodesValue) Set(s string) error {
+	var codes []int
+	for _, field := range strings.Split(s, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return fmt.Errorf("invalid exit code %q", field)
+		}
+		codes = append(codes, code)
+	}
+	*v = codes
+	return nil
+}
+
+// statusMapValue parses a comma-separated list of CODE=STATUS pairs and may
+// be repeated. Custom statuses are allowed, but not IN_PROGRESS, which would
+// leave waiters waiting.
+type statusMapValue map[int]Status
+
+func (v statusMapValue) String() string {
+	pairs := make([]string, 0, len(v))
+	for code, status := range v {
+		pairs = append(pairs, fmt.Sprintf("%d=%s", code, status))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v statusMapValue) Set(s string) error {
+	for _, pair := range strings.Split(s, ",") {
+		codeStr, status, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("invalid mapping %q (expected CODE=STATUS)", pair)
+		}
+		code, err := strconv.Atoi(codeStr)
+		if err != nil {
+			return fmt.Errorf("invalid exit code %q", codeStr)
+		}
+		if !statusPattern.MatchString(status) {
+			return fmt.Errorf("invalid status %q (expected an upper-case name)", status)
+		}
+		if Status(status) == StatusInProgress {
+			return fmt.Errorf("cannot map exit code %d to %s", code, StatusInProgress)
+		}
+		v[code] = Status(status)
+	}
+	return nil
+}
+
+// regexpValue compiles a regular expression flag
+type regexpValue struct {
+	re **regexp.Regexp
+}
+
+func (v regexpValue) String() string {
+	if v.re == nil || *v.re == nil {
+		return ""
+	}
+	return (*v.re).String()
+}
+
+func (v regexpValue) Set(s string) error {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+	*v.re = re
+	return nil
+}
+
+// rlimitListValue collects comma-separated RESOURCE=LIMIT pairs from
+// repeated --exec-rlimit flags; a later limit for a resource replaces an
+// earlier one
+type rlimitListValue []Rlimit
+
+func (v *rlimitListValue) String() string {
+	limits := make([]string, len(*v))
+	for i, limit := range *v {
+		limits[i] = limit.String()
+	}
+	return strings.Join(limits, ",")
+}
+
+func (v *rlimitListValue) Set(s string) error {
+	limits, err := ParseRlimits(s)
+	if err != nil {
+		return err
+	}
+	for _, limit := range limits {
+		*v = slices.DeleteFunc(*v, func(existing Rlimit) bool {
+			return existing.Resource == limit.Resource
+		})
+		*v = append(*v, limit)
+	}
+	return nil
+}
+
+// optionalIntValue is an integer flag that is nil unless set
+type optionalIntValue struct {
+	n **int
+}
+
+func (v optionalIntValue) String() string {
+	if v.n == nil || *v.n == nil {
+		return ""
+	}
+	return strconv.Itoa(**v.n)
+}
+
+func (v optionalIntValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v.n = &n
+	return nil
+}
+
+// ioPriorityValue is an I/O priority flag that is nil unless set
+type ioPriorityValue struct {
+	p **IOPriority
+}
+
+func (v ioPriorityValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return (*v.p).String()
+}
+
+func (v ioPriorityValue) Set(s string) error {
+	priority, err := ParseIOPriority(s)
+	if err != nil {
+		return err
+	}
+	*v.p = &priority
+	return nil
+}
+
+// Values of --exec-stdin other than a file path
+const (
+	StdinNull    = "null"
+	StdinInherit = "inherit"
+)
+
 type Config struct {
-	QueueURL       string
-	ID             string
-	Exec           string
-	Status         string
-	InstanceID     string
-	Region         string
-	Retries        int
-	PublishTimeout time.Duration
-	Timeout        time.Duration
-	LogFormat      string
-	LogLevel       string
+	QueueURL          string
+	ID                string
+	Exec              string
+	Args              []string
+	Steps             []Step
+	StepsFile         string
+	ContinueOnFailure bool
+	SignalPerStep     bool
+	ExecUser          string
+	ExecGroup         string
+	ExecDir           string
+	ExecEnv           []string
+	ExecEnvFile       string
+	ClearEnv          bool
+	ExecLogFile       string
+	ExecLogMaxSize    int
+	ExecLogMaxBackups int
+	ExecLogLines      bool
+	ExecTimeout       time.Duration
+	KillGrace         time.Duration
+	ExecRetries       int
+	ExecRetryDelay    time.Duration
+	ExecRetryBackoff  float64
+	ExecNice          int
+	ExecRlimits       []Rlimit
+	ExecOOMScoreAdj   *int
+	ExecIONice        *IOPriority
+	ExecTTY           bool
+	ExecStdin         string
+	Supervise         bool
+	WaitCloudInit     bool
+	CloudInitRoot     string
+	WaitHTTP          string
+	ExpectStatus      int
+	WaitTCP           string
+	WaitFile          string
+	WaitCmd           string
+	WaitInterval      time.Duration
+	WaitTimeout       time.Duration
+	Checks            []Check
+	CheckConcurrency  int
+	CheckTimeout      time.Duration
+	MinPass           int
+	Heartbeat         time.Duration
+	HeartbeatOutput   bool
+	SuccessExitCodes  []int
+	StatusMap         map[int]Status
+	SuccessPattern    *regexp.Regexp
+	FailurePattern    *regexp.Regexp
+	KillOnFailure     bool
+	Status            Status
+	InstanceID        string
+	Region            string
+	Retries           int
+	PublishTimeout    time.Duration
+	Timeout           time.Duration
+	LogFormat         string
+	LogLevel          string
+	DryRun            DryRunMode
+	AWS               AWSOptions
 }
 
 func ParseConfig() (*Config, error) {
-	var cfg Config
+	cfg := Config{
+		SuccessExitCodes: []int{0},
+		StatusMap:        make(map[int]Status),
+	}
 
 	flag.StringVar(&cfg.QueueURL, "queue-url", "", "(required) SQS queue URL")
 	flag.StringVar(&cfg.QueueURL, "u", "", "(required) SQS queue URL")
 	flag.StringVar(&cfg.ID, "id", "", "(required) unique signal ID for the deployment")
 	flag.StringVar(&cfg.ID, "i", "", "(required) unique signal ID for the deployment")
-	flag.StringVar(&cfg.Exec, "exec", "", "run this command and signal based on its exit code")
-	flag.StringVar(&cfg.Exec, "e", "", "run this command and signal based on its exit code")
-	flag.StringVar(&cfg.Status, "status", "", "shortcut: send SUCCESS or FAILURE without exec")
-	flag.StringVar(&cfg.Status, "s", "", "shortcut: send SUCCESS or FAILURE without exec")
+	var execs execListValue
+	flag.Var(&execs, "exec", "run this command and signal based on its exit code (repeat to run steps in order)")
+	flag.Var(&execs, "e", "run this command and signal based on its exit code (repeat to run steps in order)")
+	flag.StringVar(&cfg.StepsFile, "steps", "", "YAML file listing named steps to run in order")
+	flag.BoolVar(&cfg.ContinueOnFailure, "continue-on-failure", false, "run the remaining steps after a step fails")
+	flag.BoolVar(&cfg.SignalPerStep, "signal-per-step", false, "send a signal per step with ID <id>/<step> instead of one aggregate signal")
+	flag.BoolVar(&cfg.WaitCloudInit, "wait-cloud-init", false, "wait for cloud-init to finish and signal its result")
+	flag.StringVar(&cfg.CloudInitRoot, "cloud-init-root", "/", "root directory containing cloud-init's /run and /var/lib/cloud")
+	flag.StringVar(&cfg.WaitHTTP, "wait-http", "", "wait until a GET of this URL succeeds before signalling")
+	flag.IntVar(&cfg.ExpectStatus, "expect-status", 0, "HTTP status --wait-http must return (default: any 2xx)")
+	flag.StringVar(&cfg.WaitTCP, "wait-tcp", "", "wait until this host:port accepts TCP connections before signalling")
+	flag.StringVar(&cfg.WaitFile, "wait-file", "", "wait until this file exists before signalling")
+	flag.StringVar(&cfg.WaitCmd, "wait-cmd", "", "wait until this command exits 0 before signalling")
+	// The --ready-* probes of a supervised service become its --wait-* probes
+	var readyHTTP, readyTCP, readyFile, readyCmd string
+	flag.StringVar(&readyHTTP, "ready-http", "", "supervise: the service is ready when a GET of this URL succeeds")
+	flag.StringVar(&readyTCP, "ready-tcp", "", "supervise: the service is ready when this host:port accepts TCP connections")
+	flag.StringVar(&readyFile, "ready-file", "", "supervise: the service is ready when this file exists")
+	flag.StringVar(&readyCmd, "ready-cmd", "", "supervise: the service is ready when this command exits 0")
+	flag.DurationVar(&cfg.WaitInterval, "interval", 2*time.Second, "time between readiness probe attempts")
+	flag.DurationVar(&cfg.WaitTimeout, "wait-timeout", 5*time.Minute, "signal TIMEOUT if probes are not ready within this duration")
+	flag.Var((*checkListValue)(&cfg.Checks), "check", "run this NAME=CMD check concurrently with the others and signal on the aggregate (repeatable)")
+	flag.IntVar(&cfg.CheckConcurrency, "check-concurrency", 4, "maximum number of checks to run at once")
+	flag.DurationVar(&cfg.CheckTimeout, "check-timeout", time.Minute, "fail a check that runs longer than this duration")
+	flag.IntVar(&cfg.MinPass, "min-pass", 0, "signal SUCCESS when at least this many checks pass (default: all)")
+	flag.DurationVar(&cfg.Heartbeat, "heartbeat", 0, "publish IN_PROGRESS at this interval while the command runs (default: off)")
+	flag.BoolVar(&cfg.HeartbeatOutput, "heartbeat-output", false, "include the command's last line of output in heartbeats")
+	flag.Var((*exitCodesValue)(&cfg.SuccessExitCodes), "success-exit-codes", "comma-separated exit codes that signal SUCCESS")
+	flag.Var(statusMapValue(cfg.StatusMap), "status-map", "comma-separated CODE=STATUS mappings, e.g. 3=RETRY,4=SKIPPED")
+	flag.Var(regexpValue{&cfg.SuccessPattern}, "success-pattern", "signal SUCCESS only if a line of the command's output matches this regular expression")
+	flag.Var(regexpValue{&cfg.FailurePattern}, "failure-pattern", "signal FAILURE if a line of the command's output matches this regular expression")
+	flag.BoolVar(&cfg.KillOnFailure, "kill-on-failure-pattern", false, "terminate the command as soon as --failure-pattern matches")
+	flag.StringVar(&cfg.ExecUser, "exec-user", "", "run the command as this user (name or UID)")
+	flag.StringVar(&cfg.ExecGroup, "exec-group", "", "run the command with this primary group (name or GID)")
+	flag.StringVar(&cfg.ExecDir, "exec-dir", "", "run the command in this working directory")
+	var execEnv envListValue
+	flag.Var(&execEnv, "exec-env", "set KEY=VALUE in the command's environment (repeatable)")
+	flag.StringVar(&cfg.ExecEnvFile, "exec-env-file", "", "file of KEY=VALUE lines to add to the command's environment")
+	flag.BoolVar(&cfg.ClearEnv, "clear-env", false, "start the command with only PATH and the --exec-env variables")
+	flag.StringVar(&cfg.ExecLogFile, "exec-log-file", "", "also write the command's output to this file")
+	flag.IntVar(&cfg.ExecLogMaxSize, "exec-log-max-size", 10, "rotate --exec-log-file when it reaches this size in MB")
+	flag.IntVar(&cfg.ExecLogMaxBackups, "exec-log-max-backups", 3, "number of rotated --exec-log-file backups to keep")
+	flag.BoolVar(&cfg.ExecLogLines, "exec-log-lines", false, "log each line of the command's output with stream, signal_id and instance_id fields")
+	flag.DurationVar(&cfg.ExecTimeout, "exec-timeout", 0, "terminate --exec after this duration (default: no limit)")
+	flag.DurationVar(&cfg.KillGrace, "kill-grace", 10*time.Second, "time between SIGTERM and SIGKILL on --exec-timeout")
+	flag.IntVar(&cfg.ExecRetries, "exec-retries", 0, "rerun a failed command up to this many times before signalling FAILURE")
+	flag.DurationVar(&cfg.ExecRetryDelay, "exec-retry-delay", 5*time.Second, "time to wait before the first retry")
+	flag.Float64Var(&cfg.ExecRetryBackoff, "exec-retry-backoff", 2, "multiply the retry delay by this factor after each retry")
+	flag.IntVar(&cfg.ExecNice, "exec-nice", 0, "run the command at this niceness, from -20 to 19 (Linux only)")
+	flag.Var((*rlimitListValue)(&cfg.ExecRlimits), "exec-rlimit", "comma-separated RESOURCE=LIMIT resource limits for the command, e.g. nofile=65536,nproc=4096 (Linux only)")
+	flag.Var(optionalIntValue{&cfg.ExecOOMScoreAdj}, "exec-oom-score-adj", "set the command's oom_score_adj, from -1000 to 1000 (Linux only)")
+	flag.Var(ioPriorityValue{&cfg.ExecIONice}, "exec-ionice", "run the command in this I/O scheduling class, as CLASS[:LEVEL], e.g. idle or best-effort:7 (Linux only)")
+	flag.BoolVar(&cfg.ExecTTY, "exec-tty", false, "run the command under a pseudo-terminal and capture its output (Linux only)")
+	flag.StringVar(&cfg.ExecStdin, "exec-stdin", "", "the command's stdin: null, inherit or a file path (default: null, or inherit with supervise)")
+	flag.StringVar((*string)(&cfg.Status), "status", "", "shortcut: send this status, e.g. SUCCESS or FAILURE, without exec")
+	flag.StringVar((*string)(&cfg.Status), "s", "", "shortcut: send this status, e.g. SUCCESS or FAILURE, without exec")
 	flag.StringVar(&cfg.InstanceID, "instance-id", "", "override instance ID (default: fetch from IMDS)")
 	flag.StringVar(&cfg.InstanceID, "n", "", "override instance ID (default: fetch from IMDS)")
 	flag.StringVar(&cfg.Region, "region", "", "AWS region (default: fetch from IMDS or AWS config)")
 	flag.StringVar(&cfg.Region, "r", "", "AWS region (default: fetch from IMDS or AWS config)")
 	flag.IntVar(&cfg.Retries, "retries", 3, "transient-error retries")
 	flag.DurationVar(&cfg.PublishTimeout, "publish-timeout", 10*time.Second, "timeout per SendMessage")
-	flag.DurationVar(&cfg.Timeout, "timeout", 30*time.Second, "total operation timeout")
+	flag.DurationVar(&cfg.Timeout, "timeout", 30*time.Second, "timeout for instance metadata and publishing")
 	flag.StringVar(&cfg.LogFormat, "log-format", "console", "log format: json or console")
 	flag.StringVar(&cfg.LogLevel, "log-level", "info", "log level: debug, info, warn, or error")
+	flag.Var((*dryRunValue)(&cfg.DryRun), "dry-run", "print the SQS message instead of sending it (--dry-run=no-exec also skips --exec)")
+	flag.StringVar(&cfg.AWS.Profile, "profile", "", "named profile from the shared AWS config files")
+	flag.StringVar(&cfg.AWS.RoleARN, "role-arn", "", "IAM role to assume before publishing")
+	flag.StringVar(&cfg.AWS.WebIdentityTokenFile, "web-identity-token-file", "", "OIDC token file used to assume --role-arn")
+	flag.StringVar(&cfg.AWS.ExternalID, "external-id", "", "external ID for --role-arn")
+	flag.StringVar(&cfg.AWS.RoleSessionName, "role-session-name", DefaultRoleSessionName, "session name for --role-arn")
+	flag.DurationVar(&cfg.AWS.RoleDuration, "role-duration", 15*time.Minute, "session duration for --role-arn")
+	flag.StringVar(&cfg.AWS.EndpointURL, "endpoint-url", "", "custom SQS endpoint URL")
+	flag.BoolVar(&cfg.AWS.UseFIPSEndpoint, "use-fips-endpoint", false, "use FIPS endpoints for SQS and STS")
+	flag.BoolVar(&cfg.AWS.UseDualStackEndpoint, "use-dualstack-endpoint", false, "use dual-stack (IPv4/IPv6) endpoints for SQS and STS")
+	flag.StringVar(&cfg.AWS.HTTPSProxy, "https-proxy", "", "proxy URL for HTTPS requests to AWS (default: HTTPS_PROXY)")
+	flag.StringVar(&cfg.AWS.NoProxy, "no-proxy", "", "comma-separated hosts, domains or CIDRs that bypass the proxy")
+	flag.StringVar(&cfg.AWS.CABundle, "ca-bundle", "", "PEM file with additional CA certificates to trust")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `USAGE:
-  tcsignal-aws [flags]
+  tcsignal-aws [flags] [-- command [args...]]
+  tcsignal-aws supervise [flags] -- command [args...]
 
 FLAGS:
   -u, --queue-url string     (required) SQS queue URL
   -i, --id string            (required) unique signal ID for the deployment
-  -e, --exec string          run this command and signal based on its exit code
-  -s, --status string        shortcut: send "SUCCESS" or "FAILURE" without exec
+  -e, --exec string          run this command with sh -c and signal based on its exit code (repeat to run steps in order)
+  -- command [args...]       run this command directly (no shell) and signal based on its exit code
+  --steps string             YAML file listing named steps to run in order
+  --continue-on-failure      run the remaining steps after a step fails
+  --signal-per-step          send a signal per step with ID <id>/<step> instead of one aggregate signal
+  --wait-cloud-init          wait for cloud-init to finish and signal its result
+  --cloud-init-root string   root directory containing cloud-init's /run and /var/lib/cloud (default "/")
+  --wait-http string         wait until a GET of this URL succeeds before signalling
+  --expect-status int        HTTP status --wait-http must return (default: any 2xx)
+  --wait-tcp string          wait until this host:port accepts TCP connections before signalling
+  --wait-file string         wait until this file exists before signalling
+  --wait-cmd string          wait until this command exits 0 before signalling
+  --ready-http string        supervise: the service is ready when a GET of this URL succeeds
+  --ready-tcp string         supervise: the service is ready when this host:port accepts TCP connections
+  --ready-file string        supervise: the service is ready when this file exists
+  --ready-cmd string         supervise: the service is ready when this command exits 0
+  --interval duration        time between readiness probe attempts (default 2s)
+  --wait-timeout duration    signal TIMEOUT if probes are not ready within this duration (default 5m)
+  --check NAME=CMD           run this check concurrently with the others and signal on the aggregate (repeatable)
+  --check-concurrency int    maximum number of checks to run at once (default 4)
+  --check-timeout duration   fail a check that runs longer than this duration (default 1m)
+  --min-pass int             signal SUCCESS when at least this many checks pass (default: all)
+  --heartbeat duration       publish IN_PROGRESS at this interval while the command runs (default: off)
+  --heartbeat-output         include the command's last line of output in heartbeats
+  --success-exit-codes list  comma-separated exit codes that signal SUCCESS (default 0)
+  --status-map list          comma-separated CODE=STATUS mappings, e.g. 3=RETRY,4=SKIPPED
+  --success-pattern regex    signal SUCCESS only if a line of the command's output matches this regular expression
+  --failure-pattern regex    signal FAILURE if a line of the command's output matches this regular expression
+  --kill-on-failure-pattern  terminate the command as soon as --failure-pattern matches
+  --exec-user string         run the command as this user (name or UID)
+  --exec-group string        run the command with this primary group (name or GID)
+  --exec-dir string          run the command in this working directory
+  --exec-env KEY=VALUE       set a variable in the command's environment (repeatable)
+  --exec-env-file string     file of KEY=VALUE lines to add to the command's environment
+  --clear-env                start the command with only PATH and the --exec-env variables
+  --exec-log-file string     also write the command's output to this file
+  --exec-log-max-size int    rotate --exec-log-file when it reaches this size in MB (default 10)
+  --exec-log-max-backups int number of rotated --exec-log-file backups to keep (default 3)
+  --exec-log-lines           log each line of the command's output with stream, signal_id and instance_id fields
+  --exec-timeout duration    terminate --exec after this duration (default: no limit)
+  --kill-grace duration      time between SIGTERM and SIGKILL on --exec-timeout (default 10s)
+  --exec-retries int         rerun a failed command up to this many times before signalling FAILURE (default 0)
+  --exec-retry-delay duration time to wait before the first retry (default 5s)
+  --exec-retry-backoff float multiply the retry delay by this factor after each retry (default 2)
+  --exec-nice int            run the command at this niceness, from -20 to 19 (Linux only)
+  --exec-rlimit list         comma-separated RESOURCE=LIMIT resource limits for the command, e.g. nofile=65536,nproc=4096 (Linux only)
+  --exec-oom-score-adj int   set the command's oom_score_adj, from -1000 to 1000 (Linux only)
+  --exec-ionice class        run the command in this I/O scheduling class, as CLASS[:LEVEL], e.g. idle or best-effort:7 (Linux only)
+  --exec-tty                 run the command under a pseudo-terminal and capture its output (Linux only)
+  --exec-stdin string        the command's stdin: null, inherit or a file path (default: null, or inherit with supervise)
+  -s, --status string        shortcut: send this status, e.g. SUCCESS or FAILURE, without exec
   -n, --instance-id string   override instance ID (default: fetch from IMDS)
   -r, --region string        AWS region (default: fetch from IMDS or AWS config)
   --retries int              transient-error retries (default 3)
   --publish-timeout duration timeout per SendMessage (default 10s)
-  --timeout duration         total operation timeout (default 30s)
+  --timeout duration         timeout for instance metadata and publishing (default 30s)
   --log-format string        log format: json or console (default "console")
   --log-level string         log level: debug, info, warn, or error (default "info")
+  --dry-run                  print the SQS message instead of sending it (=no-exec skips --exec)
+  --profile string           named profile from the shared AWS config files
+  --role-arn string          IAM role to assume before publishing
+  --web-identity-token-file string OIDC token file used to assume --role-arn
+  --external-id string       external ID for --role-arn
+  --role-session-name string session name for --role-arn (default "tcsignal-aws")
+  --role-duration duration   session duration for --role-arn (default 15m)
+  --endpoint-url string      custom SQS endpoint URL
+  --use-fips-endpoint        use FIPS endpoints for SQS and STS
+  --use-dualstack-endpoint   use dual-stack (IPv4/IPv6) endpoints for SQS and STS
+  --https-proxy string       proxy URL for HTTPS requests to AWS (default: HTTPS_PROXY)
+  --no-proxy string          comma-separated hosts, domains or CIDRs that bypass the proxy
+  --ca-bundle string         PEM file with additional CA certificates to trust
   --help                     show usage
 `)
 	}
 
-	flag.Parse()
+	// "supervise" is the only subcommand
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "supervise" {
+		cfg.Supervise = true
+		args = args[1:]
+	}
+
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return nil, err
+	}
 
 	// Validate required flags
 	if cfg.QueueURL == "" {
@@ -73,14 +499,199 @@ FLAGS:
 		return nil, fmt.Errorf("--id is required")
 	}
 
+	// Everything after "--" is the command to run without a shell
+	if rest := flag.Args(); len(rest) > 0 {
+		if separator := len(args) - len(rest) - 1; separator < 0 || args[separator] != "--" {
+			return nil, fmt.Errorf("unexpected argument %q (use -- to separate the command to run)", rest[0])
+		}
+		cfg.Args = rest
+	}
+
+	if len(execs) > 0 && len(cfg.Args) > 0 {
+		return nil, fmt.Errorf("--exec cannot be combined with a command after --")
+	}
+
+	// A repeated --exec runs each command as a numbered step
+	switch {
+	case len(execs) == 1:
+		cfg.Exec = execs[0]
+	case len(execs) > 1:
+		for i, command := range execs {
+			cfg.Steps = append(cfg.Steps, Step{Name: fmt.Sprintf("step-%d", i+1), Run: command})
+		}
+	}
+
+	if cfg.StepsFile != "" {
+		if len(execs) > 0 || len(cfg.Args) > 0 {
+			return nil, fmt.Errorf("--steps cannot be combined with --exec or a command after --")
+		}
+		steps, err := LoadSteps(cfg.StepsFile)
+		if err != nil {
+			return nil, fmt.Errorf("--steps: %w", err)
+		}
+		cfg.Steps = steps
+	}
+
+	hasReady := readyHTTP != "" || readyTCP != "" || readyFile != "" || readyCmd != ""
+	if hasReady && !cfg.Supervise {
+		return nil, fmt.Errorf("--ready-http, --ready-tcp, --ready-file and --ready-cmd require supervise (use --wait-* instead)")
+	}
+	if cfg.Supervise {
+		if cfg.WaitHTTP != "" || cfg.WaitTCP != "" || cfg.WaitFile != "" || cfg.WaitCmd != "" {
+			return nil, fmt.Errorf("supervise uses --ready-* probes instead of --wait-*")
+		}
+		cfg.WaitHTTP, cfg.WaitTCP, cfg.WaitFile, cfg.WaitCmd = readyHTTP, readyTCP, readyFile, readyCmd
+	}
+
 	// Validate that either --exec or --status is provided
-	if cfg.Exec == "" && cfg.Status == "" {
-		return nil, fmt.Errorf("either --exec or --status must be provided")
+	hasProbes := cfg.WaitHTTP != "" || cfg.WaitTCP != "" || cfg.WaitFile != "" || cfg.WaitCmd != ""
+	if cfg.Exec == "" && len(cfg.Args) == 0 && len(cfg.Steps) == 0 && cfg.Status == "" && !hasProbes && !cfg.WaitCloudInit && len(cfg.Checks) == 0 {
+		return nil, fmt.Errorf("either --exec, --status, --check or a --wait-* probe must be provided")
+	}
+
+	if cfg.Supervise {
+		if cfg.Exec == "" && len(cfg.Args) == 0 {
+			return nil, fmt.Errorf("supervise requires a command after -- or --exec")
+		}
+		if len(cfg.Steps) > 0 || cfg.Status != "" || cfg.WaitCloudInit {
+			return nil, fmt.Errorf("supervise cannot be combined with --steps, --status or --wait-cloud-init")
+		}
+		if cfg.ExecTimeout > 0 || cfg.Heartbeat > 0 || cfg.DryRun == DryRunNoExec {
+			return nil, fmt.Errorf("supervise cannot be combined with --exec-timeout, --heartbeat or --dry-run=no-exec")
+		}
+	}
+
+	if cfg.WaitCloudInit && (cfg.Exec != "" || len(cfg.Args) > 0 || len(cfg.Steps) > 0 || cfg.Status != "") {
+		return nil, fmt.Errorf("--wait-cloud-init cannot be combined with --exec, --steps or --status")
+	}
+
+	// Validate checks
+	if len(cfg.Checks) > 0 && (cfg.Status != "" || cfg.WaitCloudInit || cfg.Supervise) {
+		return nil, fmt.Errorf("--check cannot be combined with --status, --wait-cloud-init or supervise")
+	}
+	if cfg.CheckConcurrency < 1 {
+		return nil, fmt.Errorf("--check-concurrency must be at least 1")
+	}
+	if cfg.CheckTimeout < 0 {
+		return nil, fmt.Errorf("--check-timeout must not be negative")
+	}
+	if cfg.MinPass < 0 || cfg.MinPass > len(cfg.Checks) {
+		return nil, fmt.Errorf("--min-pass must be between 0 and the number of checks (%d)", len(cfg.Checks))
+	}
+
+	// Validate readiness probes
+	if hasProbes && cfg.Status != "" {
+		return nil, fmt.Errorf("--wait-* probes cannot be combined with --status")
+	}
+
+	if cfg.WaitHTTP != "" {
+		u, err := url.Parse(cfg.WaitHTTP)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("--wait-http must be an http or https URL")
+		}
+	}
+
+	if cfg.ExpectStatus != 0 && (cfg.ExpectStatus < 100 || cfg.ExpectStatus > 599) {
+		return nil, fmt.Errorf("--expect-status must be an HTTP status code")
+	}
+
+	if cfg.WaitTCP != "" {
+		if _, _, err := net.SplitHostPort(cfg.WaitTCP); err != nil {
+			return nil, fmt.Errorf("--wait-tcp must be host:port: %w", err)
+		}
+	}
+
+	if cfg.WaitInterval <= 0 {
+		return nil, fmt.Errorf("--interval must be positive")
+	}
+
+	if cfg.WaitTimeout < 0 {
+		return nil, fmt.Errorf("--wait-timeout must not be negative")
+	}
+
+	if (cfg.ContinueOnFailure || cfg.SignalPerStep) && len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("--continue-on-failure and --signal-per-step require multiple --exec flags or --steps")
+	}
+
+	if cfg.ExecEnvFile != "" {
+		env, err := LoadEnvFile(cfg.ExecEnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("--exec-env-file: %w", err)
+		}
+		cfg.ExecEnv = env
+	}
+	// --exec-env flags come after the file so they take precedence
+	cfg.ExecEnv = append(cfg.ExecEnv, execEnv...)
+
+	if cfg.ExecLogMaxSize < 0 || cfg.ExecLogMaxBackups < 0 {
+		return nil, fmt.Errorf("--exec-log-max-size and --exec-log-max-backups must not be negative")
+	}
+
+	if cfg.ExecTimeout < 0 {
+		return nil, fmt.Errorf("--exec-timeout must not be negative")
+	}
+
+	if cfg.Heartbeat < 0 {
+		return nil, fmt.Errorf("--heartbeat must not be negative")
+	}
+
+	if cfg.HeartbeatOutput && cfg.Heartbeat == 0 {
+		return nil, fmt.Errorf("--heartbeat-output requires --heartbeat")
+	}
+
+	hasPatterns := cfg.SuccessPattern != nil || cfg.FailurePattern != nil
+	if hasPatterns && cfg.Exec == "" && len(cfg.Args) == 0 && len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("--success-pattern and --failure-pattern require a command")
+	}
+	if hasPatterns && cfg.Supervise {
+		return nil, fmt.Errorf("supervise cannot be combined with --success-pattern or --failure-pattern")
+	}
+	if cfg.KillOnFailure && cfg.FailurePattern == nil {
+		return nil, fmt.Errorf("--kill-on-failure-pattern requires --failure-pattern")
+	}
+
+	if cfg.ExecRetries < 0 || cfg.ExecRetryDelay < 0 {
+		return nil, fmt.Errorf("--exec-retries and --exec-retry-delay must not be negative")
+	}
+	if cfg.ExecRetryBackoff < 1 {
+		return nil, fmt.Errorf("--exec-retry-backoff must be at least 1")
+	}
+	if cfg.ExecRetries > 0 && cfg.Exec == "" && len(cfg.Args) == 0 && len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("--exec-retries requires a command")
+	}
+	if cfg.ExecRetries > 0 && cfg.Supervise {
+		return nil, fmt.Errorf("supervise cannot be combined with --exec-retries")
+	}
+
+	if cfg.ExecNice < -20 || cfg.ExecNice > 19 {
+		return nil, fmt.Errorf("--exec-nice must be between -20 and 19")
+	}
+	if cfg.ExecOOMScoreAdj != nil && (*cfg.ExecOOMScoreAdj < -1000 || *cfg.ExecOOMScoreAdj > 1000) {
+		return nil, fmt.Errorf("--exec-oom-score-adj must be between -1000 and 1000")
+	}
+	hasLimits := cfg.ExecNice != 0 || len(cfg.ExecRlimits) > 0 || cfg.ExecOOMScoreAdj != nil || cfg.ExecIONice != nil
+	if hasLimits && cfg.Exec == "" && len(cfg.Args) == 0 && len(cfg.Steps) == 0 && len(cfg.Checks) == 0 && cfg.WaitCmd == "" {
+		return nil, fmt.Errorf("--exec-nice, --exec-ionice, --exec-rlimit and --exec-oom-score-adj require a command")
+	}
+
+	if cfg.ExecStdin != "" && cfg.ExecStdin != StdinNull && cfg.ExecStdin != StdinInherit {
+		if _, err := os.Stat(cfg.ExecStdin); err != nil {
+			return nil, fmt.Errorf("--exec-stdin: %w", err)
+		}
+	}
+	if (cfg.ExecTTY || cfg.ExecStdin != "") && cfg.Exec == "" && len(cfg.Args) == 0 && len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("--exec-tty and --exec-stdin require a command")
+	}
+
+	if cfg.KillGrace < 0 {
+		return nil, fmt.Errorf("--kill-grace must not be negative")
 	}
 
 	// Validate --status values if provided
-	if cfg.Status != "" && cfg.Status != "SUCCESS" && cfg.Status != "FAILURE" {
-		return nil, fmt.Errorf("--status must be either SUCCESS or FAILURE")
+	if cfg.Status != "" {
+		if _, err := ParseStatus(string(cfg.Status)); err != nil {
+			return nil, fmt.Errorf("--status: %w", err)
+		}
 	}
 
 	// Validate --log-format values
@@ -93,5 +704,169 @@ FLAGS:
 		return nil, fmt.Errorf("--log-level must be one of: debug, info, warn, error")
 	}
 
+	// Validate assume-role options
+	if cfg.AWS.RoleARN != "" && !strings.HasPrefix(cfg.AWS.RoleARN, "arn:") {
+		return nil, fmt.Errorf("--role-arn must be an IAM role ARN")
+	}
+
+	if cfg.AWS.ExternalID != "" && cfg.AWS.RoleARN == "" {
+		return nil, fmt.Errorf("--external-id requires --role-arn")
+	}
+
+	if cfg.AWS.WebIdentityTokenFile != "" {
+		if cfg.AWS.RoleARN == "" {
+			return nil, fmt.Errorf("--web-identity-token-file requires --role-arn")
+		}
+		if cfg.AWS.ExternalID != "" {
+			return nil, fmt.Errorf("--external-id cannot be used with --web-identity-token-file")
+		}
+	}
+
+	if cfg.AWS.RoleDuration < 15*time.Minute || cfg.AWS.RoleDuration > 12*time.Hour {
+		return nil, fmt.Errorf("--role-duration must be between 15m and 12h")
+	}
+
+	// Validate endpoint options
+	if cfg.AWS.EndpointURL != "" {
+		u, err := url.Parse(cfg.AWS.EndpointURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("--endpoint-url must be an http or https URL")
+		}
+
+		if cfg.AWS.UseFIPSEndpoint || cfg.AWS.UseDualStackEndpoint {
+			return nil, fmt.Errorf("--endpoint-url cannot be combined with --use-fips-endpoint or --use-dualstack-endpoint")
+		}
+	}
+
+	// Validate network options
+	if cfg.AWS.HTTPSProxy != "" {
+		u, err := url.Parse(cfg.AWS.HTTPSProxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("--https-proxy must be an http or https URL")
+		}
+	}
+
+	if cfg.AWS.CABundle != "" {
+		if _, err := os.Stat(cfg.AWS.CABundle); err != nil {
+			return nil, fmt.Errorf("--ca-bundle: %w", err)
+		}
+	}
+
 	return &cfg, nil
 }
+
+// ExecSpec returns the command to run: the --exec shell command line, or
+// the argv given after --
+func (c *Config) ExecSpec() ExecSpec {
+	spec := c.withExecOptions(ExecSpec{
+		Command: c.Exec,
+		Args:    c.Args,
+	})
+	spec.TTY = c.ExecTTY
+	return spec
+}
+
+// StepExecSpec returns the command to run for a step
+func (c *Config) StepExecSpec(step Step) ExecSpec {
+	spec := c.withExecOptions(step.ExecSpec())
+	spec.TTY = c.ExecTTY
+	return spec
+}
+
+// OpenExecStdin opens the command's stdin as set by --exec-stdin: nothing
+// for null, this process's stdin for inherit, or a file. Supervised
+// services inherit stdin by default. The returned function closes a file
+// opened for it.
+func (c *Config) OpenExecStdin() (io.Reader, func(), error) {
+	mode := c.ExecStdin
+	if mode == "" && c.Supervise {
+		mode = StdinInherit
+	}
+
+	switch mode {
+	case "", StdinNull:
+		return nil, func() {}, nil
+	case StdinInherit:
+		return os.Stdin, func() {}, nil
+	}
+
+	f, err := os.Open(mode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("--exec-stdin: %w", err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// withExecOptions applies the user, working directory and environment
+// options to spec
+func (c *Config) withExecOptions(spec ExecSpec) ExecSpec {
+	spec.User = c.ExecUser
+	spec.Group = c.ExecGroup
+	spec.Dir = c.ExecDir
+
+	if c.ClearEnv || len(c.ExecEnv) > 0 {
+		env := []string{}
+		if !c.ClearEnv {
+			env = os.Environ()
+		} else if path, ok := os.LookupEnv("PATH"); ok {
+			env = append(env, "PATH="+path)
+		}
+		spec.Env = append(env, c.ExecEnv...)
+	}
+
+	return spec
+}
+
+// ExecLimits returns the priority and resource limits to apply to commands
+func (c *Config) ExecLimits() ResourceLimits {
+	return ResourceLimits{
+		Nice:        c.ExecNice,
+		Rlimits:     c.ExecRlimits,
+		OOMScoreAdj: c.ExecOOMScoreAdj,
+		IOPriority:  c.ExecIONice,
+	}
+}
+
+// CheckExecSpec returns the command to run for a check, with the same user,
+// directory and environment as --exec
+func (c *Config) CheckExecSpec(check Check) ExecSpec {
+	return c.withExecOptions(ExecSpec{Command: check.Command})
+}
+
+// Probes returns the readiness probes to wait for, in the order HTTP, TCP,
+// file, command. Command probes run with executor.
+func (c *Config) Probes(executor Executor) []Probe {
+	var probes []Probe
+	if c.WaitHTTP != "" {
+		probes = append(probes, &HTTPProbe{URL: c.WaitHTTP, ExpectStatus: c.ExpectStatus})
+	}
+	if c.WaitTCP != "" {
+		probes = append(probes, &TCPProbe{Address: c.WaitTCP})
+	}
+	if c.WaitFile != "" {
+		probes = append(probes, &FileProbe{Path: c.WaitFile})
+	}
+	if c.WaitCmd != "" {
+		probes = append(probes, &CommandProbe{Command: c.WaitCmd, Executor: executor})
+	}
+	return probes
+}
+
+// ExitStatus maps a command exit code to the status to signal. --status-map
+// takes precedence; otherwise codes in --success-exit-codes are SUCCESS and
+// everything else is FAILURE.
+func (c *Config) ExitStatus(exitCode int) Status {
+	if status, ok := c.StatusMap[exitCode]; ok {
+		return status
+	}
+
+	successCodes := c.SuccessExitCodes
+	if len(successCodes) == 0 {
+		successCodes = []int{0}
+	}
+	if slices.Contains(successCodes, exitCode) {
+		return StatusSuccess
+	}
+
+	return StatusFailure
+}
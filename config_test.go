@@ -2,7 +2,12 @@ package signal
 
 import (
 	"flag"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -134,7 +139,7 @@ func TestParseConfig_MissingExecAndStatus(t *testing.T) {
 		t.Fatal("Expected error for missing exec and status, got nil")
 	}
 
-	if err.Error() != "either --exec or --status must be provided" {
+	if err.Error() != "either --exec, --status, --check or a --wait-* probe must be provided" {
 		t.Errorf("Expected specific error message, got: %s", err.Error())
 	}
 }
@@ -158,13 +163,13 @@ func TestParseConfig_InvalidStatus(t *testing.T) {
 		t.Fatal("Expected error for invalid status, got nil")
 	}
 
-	if err.Error() != "--status must be either SUCCESS or FAILURE" {
+	if err.Error() != `--status: unknown status "INVALID" (expected one of SUCCESS, FAILURE, IN_PROGRESS, TIMEOUT, CANCELLED, SKIPPED)` {
 		t.Errorf("Expected specific error message, got: %s", err.Error())
 	}
 }
 
 func TestParseConfig_ValidStatus(t *testing.T) {
-	testCases := []string{"SUCCESS", "FAILURE"}
+	testCases := []string{"SUCCESS", "FAILURE", "IN_PROGRESS", "TIMEOUT", "CANCELLED", "SKIPPED"}
 
 	for _, status := range testCases {
 		t.Run("Status_"+status, func(t *testing.T) {
@@ -186,7 +191,7 @@ func TestParseConfig_ValidStatus(t *testing.T) {
 				t.Fatalf("Expected no error for valid status %s, got: %v", status, err)
 			}
 
-			if cfg.Status != status {
+			if cfg.Status != Status(status) {
 				t.Errorf("Expected Status to be %s, got: %s", status, cfg.Status)
 			}
 		})
@@ -271,3 +276,1061 @@ func TestParseConfig_Defaults(t *testing.T) {
 		t.Errorf("Expected default LogFormat to be console, got: %s", cfg.LogFormat)
 	}
 }
+
+func TestParseConfig_AssumeRole(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--status", "SUCCESS",
+		"--profile", "ci",
+		"--role-arn", "arn:aws:iam::123456789012:role/signal-publisher",
+		"--external-id", "tooling",
+		"--role-session-name", "web-42",
+		"--role-duration", "1h",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.AWS.RoleARN != "arn:aws:iam::123456789012:role/signal-publisher" {
+		t.Errorf("Expected RoleARN to be set correctly, got: %s", cfg.AWS.RoleARN)
+	}
+
+	if cfg.AWS.Profile != "ci" {
+		t.Errorf("Expected Profile to be ci, got: %s", cfg.AWS.Profile)
+	}
+
+	if cfg.AWS.ExternalID != "tooling" {
+		t.Errorf("Expected ExternalID to be tooling, got: %s", cfg.AWS.ExternalID)
+	}
+
+	if cfg.AWS.RoleSessionName != "web-42" {
+		t.Errorf("Expected RoleSessionName to be web-42, got: %s", cfg.AWS.RoleSessionName)
+	}
+
+	if cfg.AWS.RoleDuration != time.Hour {
+		t.Errorf("Expected RoleDuration to be 1h, got: %v", cfg.AWS.RoleDuration)
+	}
+}
+
+func TestParseConfig_InvalidAWSOptions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "external id without role",
+			args:          []string{"--external-id", "tooling"},
+			expectedError: "--external-id requires --role-arn",
+		},
+		{
+			name:          "malformed role arn",
+			args:          []string{"--role-arn", "signal-publisher"},
+			expectedError: "--role-arn must be an IAM role ARN",
+		},
+		{
+			name:          "endpoint without scheme",
+			args:          []string{"--endpoint-url", "sqs.internal:9324"},
+			expectedError: "--endpoint-url must be an http or https URL",
+		},
+		{
+			name:          "endpoint with fips",
+			args:          []string{"--endpoint-url", "https://vpce.example.com", "--use-fips-endpoint"},
+			expectedError: "--endpoint-url cannot be combined with --use-fips-endpoint or --use-dualstack-endpoint",
+		},
+		{
+			name:          "proxy without scheme",
+			args:          []string{"--https-proxy", "proxy.internal:3128"},
+			expectedError: "--https-proxy must be an http or https URL",
+		},
+		{
+			name:          "web identity without role",
+			args:          []string{"--web-identity-token-file", "/var/run/secrets/token"},
+			expectedError: "--web-identity-token-file requires --role-arn",
+		},
+		{
+			name:          "web identity with external id",
+			args:          []string{"--role-arn", "arn:aws:iam::123456789012:role/x", "--web-identity-token-file", "/var/run/secrets/token", "--external-id", "tooling"},
+			expectedError: "--external-id cannot be used with --web-identity-token-file",
+		},
+		{
+			name:          "duration too short",
+			args:          []string{"--role-arn", "arn:aws:iam::123456789012:role/x", "--role-duration", "5m"},
+			expectedError: "--role-duration must be between 15m and 12h",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+				"--status", "SUCCESS",
+			}, tc.args...)
+
+			_, err := ParseConfig()
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			if err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got: %s", tc.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseConfig_EndpointOptions(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-gov-west-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--status", "SUCCESS",
+		"--use-fips-endpoint",
+		"--use-dualstack-endpoint",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !cfg.AWS.UseFIPSEndpoint {
+		t.Error("Expected UseFIPSEndpoint to be true")
+	}
+
+	if !cfg.AWS.UseDualStackEndpoint {
+		t.Error("Expected UseDualStackEndpoint to be true")
+	}
+
+	if cfg.AWS.EndpointURL != "" {
+		t.Errorf("Expected EndpointURL to be empty, got: %s", cfg.AWS.EndpointURL)
+	}
+}
+
+func TestParseConfig_DryRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected DryRunMode
+	}{
+		{"off", nil, DryRunOff},
+		{"bool", []string{"--dry-run"}, DryRunOn},
+		{"no-exec", []string{"--dry-run=no-exec"}, DryRunNoExec},
+		{"false", []string{"--dry-run=false"}, DryRunOff},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+				"--exec", "echo hello",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if cfg.DryRun != tc.expected {
+				t.Errorf("Expected DryRun %q, got: %q", tc.expected, cfg.DryRun)
+			}
+		})
+	}
+}
+
+func TestParseConfig_ArgvCommand(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--",
+		"/opt/app/install", "--name", "it's $HOME",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []string{"/opt/app/install", "--name", "it's $HOME"}
+	if len(cfg.Args) != len(expected) {
+		t.Fatalf("Expected Args %v, got: %v", expected, cfg.Args)
+	}
+	for i := range expected {
+		if cfg.Args[i] != expected[i] {
+			t.Errorf("Expected Args[%d] to be %q, got: %q", i, expected[i], cfg.Args[i])
+		}
+	}
+
+	spec := cfg.ExecSpec()
+	if spec.Command != "" || len(spec.Args) != 3 {
+		t.Errorf("Expected argv exec spec, got: %+v", spec)
+	}
+}
+
+func TestParseConfig_InvalidArgvCommand(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "positional without separator",
+			args:          []string{"--status", "SUCCESS", "extra"},
+			expectedError: `unexpected argument "extra" (use -- to separate the command to run)`,
+		},
+		{
+			name:          "exec and argv",
+			args:          []string{"--exec", "echo hello", "--", "echo", "hello"},
+			expectedError: "--exec cannot be combined with a command after --",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			_, err := ParseConfig()
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			if err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got: %s", tc.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseConfig_ExitCodeMapping(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--exec", "./install.sh",
+		"--success-exit-codes", "0,2,100",
+		"--status-map", "3=RETRY,4=SKIPPED",
+		"--status-map", "2=REBOOT_REQUIRED",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	testCases := []struct {
+		exitCode int
+		expected Status
+	}{
+		{0, "SUCCESS"},
+		{100, "SUCCESS"},
+		{2, "REBOOT_REQUIRED"}, // status map takes precedence
+		{3, "RETRY"},
+		{4, "SKIPPED"},
+		{1, "FAILURE"},
+	}
+
+	for _, tc := range testCases {
+		if got := cfg.ExitStatus(tc.exitCode); got != tc.expected {
+			t.Errorf("Expected exit code %d to map to %s, got: %s", tc.exitCode, tc.expected, got)
+		}
+	}
+}
+
+func TestParseConfig_InvalidExitCodeMapping(t *testing.T) {
+	testCases := [][]string{
+		{"--success-exit-codes", "0,two"},
+		{"--status-map", "3"},
+		{"--status-map", "x=RETRY"},
+		{"--status-map", "3=retry"},
+		{"--status-map", "3=IN_PROGRESS"},
+	}
+
+	for _, args := range testCases {
+		t.Run(args[1], func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+				"--exec", "./install.sh",
+			}, args...)
+
+			if _, err := ParseConfig(); err == nil {
+				t.Fatalf("Expected error for %v, got nil", args)
+			}
+		})
+	}
+}
+
+func TestConfig_ExitStatusDefaults(t *testing.T) {
+	var cfg Config
+
+	if got := cfg.ExitStatus(0); got != "SUCCESS" {
+		t.Errorf("Expected exit code 0 to be SUCCESS by default, got: %s", got)
+	}
+
+	if got := cfg.ExitStatus(1); got != "FAILURE" {
+		t.Errorf("Expected exit code 1 to be FAILURE by default, got: %s", got)
+	}
+}
+
+func TestParseConfig_RepeatedExec(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--exec", "./packages.sh",
+		"-e", "./app.sh",
+		"--continue-on-failure",
+		"--signal-per-step",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Exec != "" {
+		t.Errorf("Expected Exec to be empty for multiple commands, got: %s", cfg.Exec)
+	}
+
+	expected := []Step{
+		{Name: "step-1", Run: "./packages.sh"},
+		{Name: "step-2", Run: "./app.sh"},
+	}
+	if !reflect.DeepEqual(cfg.Steps, expected) {
+		t.Errorf("Expected steps %+v, got: %+v", expected, cfg.Steps)
+	}
+
+	if !cfg.ContinueOnFailure || !cfg.SignalPerStep {
+		t.Errorf("Expected ContinueOnFailure and SignalPerStep to be set, got: %+v", cfg)
+	}
+}
+
+func TestParseConfig_StepsFile(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	path := writeStepsFile(t, "steps:\n  - name: packages\n    run: ./packages.sh\n")
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--steps", path,
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(cfg.Steps) != 1 || cfg.Steps[0].Name != "packages" {
+		t.Errorf("Expected steps from file, got: %+v", cfg.Steps)
+	}
+}
+
+func TestParseConfig_InvalidSteps(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"steps with exec", []string{"--steps", "steps.yaml", "--exec", "./a.sh"}, "--steps cannot be combined"},
+		{"steps with argv", []string{"--steps", "steps.yaml", "--", "./a.sh"}, "--steps cannot be combined"},
+		{"missing steps file", []string{"--steps", "does-not-exist.yaml"}, "--steps:"},
+		{"continue without steps", []string{"--exec", "./a.sh", "--continue-on-failure"}, "require multiple --exec flags or --steps"},
+		{"per-step without steps", []string{"--exec", "./a.sh", "--signal-per-step"}, "require multiple --exec flags or --steps"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			_, err := ParseConfig()
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_ExecEnvironment(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	envFile := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(envFile, []byte("APP_ENV=staging\nDB_HOST=db.internal\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--exec", "./install.sh",
+		"--exec-user", "app",
+		"--exec-group", "app",
+		"--exec-dir", "/opt/app",
+		"--exec-env-file", envFile,
+		"--exec-env", "APP_ENV=production",
+		"--exec-env", "GREETING=hello world",
+		"--clear-env",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	spec := cfg.ExecSpec()
+	if spec.User != "app" || spec.Group != "app" || spec.Dir != "/opt/app" {
+		t.Errorf("Expected user, group and dir to be set, got: %+v", spec)
+	}
+
+	// Only PATH is kept, and --exec-env overrides the env file
+	expected := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"APP_ENV=staging",
+		"DB_HOST=db.internal",
+		"APP_ENV=production",
+		"GREETING=hello world",
+	}
+	if !reflect.DeepEqual(spec.Env, expected) {
+		t.Errorf("Expected env %q, got: %q", expected, spec.Env)
+	}
+
+	stepSpec := cfg.StepExecSpec(Step{Name: "app", Run: "./app.sh"})
+	if stepSpec.Command != "./app.sh" || stepSpec.User != "app" || !reflect.DeepEqual(stepSpec.Env, expected) {
+		t.Errorf("Expected exec options to apply to steps, got: %+v", stepSpec)
+	}
+}
+
+func TestConfig_ExecSpecInheritsEnvironment(t *testing.T) {
+	cfg := Config{Exec: "./install.sh"}
+	if spec := cfg.ExecSpec(); spec.Env != nil {
+		t.Errorf("Expected nil env to inherit the environment, got: %q", spec.Env)
+	}
+
+	t.Setenv("TCSIGNAL_TEST_INHERITED", "1")
+	cfg.ExecEnv = []string{"APP_ENV=production"}
+	env := cfg.ExecSpec().Env
+	if !slices.Contains(env, "TCSIGNAL_TEST_INHERITED=1") || env[len(env)-1] != "APP_ENV=production" {
+		t.Errorf("Expected inherited environment followed by --exec-env, got: %q", env)
+	}
+
+	cfg.ExecEnv = nil
+	cfg.ClearEnv = true
+	if env := cfg.ExecSpec().Env; env == nil || slices.Contains(env, "TCSIGNAL_TEST_INHERITED=1") {
+		t.Errorf("Expected --clear-env to drop inherited variables, got: %q", env)
+	}
+}
+
+func TestParseConfig_InvalidExecEnvironment(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"missing value", []string{"--exec-env", "APP_ENV"}, "invalid variable"},
+		{"bad name", []string{"--exec-env", "1APP=x"}, "invalid variable"},
+		{"missing env file", []string{"--exec-env-file", "does-not-exist.env"}, "--exec-env-file:"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+				"--exec", "./install.sh",
+			}, tc.args...)
+
+			_, err := ParseConfig()
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Probes(t *testing.T) {
+	// Reset flag set for testing
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	os.Args = []string{
+		"tcsignal-aws",
+		"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		"--id", "test-signal-123",
+		"--wait-http", "http://localhost:8080/health",
+		"--expect-status", "200",
+		"--wait-tcp", ":5432",
+		"--wait-file", "/run/app.ready",
+		"--wait-cmd", "pg_isready",
+		"--interval", "5s",
+		"--wait-timeout", "10m",
+	}
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.WaitInterval != 5*time.Second || cfg.WaitTimeout != 10*time.Minute {
+		t.Errorf("Expected interval 5s and wait timeout 10m, got: %v, %v", cfg.WaitInterval, cfg.WaitTimeout)
+	}
+
+	probes := cfg.Probes(NewMockExecutor())
+	expected := []string{
+		"http http://localhost:8080/health",
+		"tcp :5432",
+		"file /run/app.ready",
+		"command pg_isready",
+	}
+	if len(probes) != len(expected) {
+		t.Fatalf("Expected %d probes, got %d", len(expected), len(probes))
+	}
+	for i, probe := range probes {
+		if probe.String() != expected[i] {
+			t.Errorf("Probe %d: expected %q, got %q", i, expected[i], probe.String())
+		}
+	}
+
+	if probe := probes[0].(*HTTPProbe); probe.ExpectStatus != 200 {
+		t.Errorf("Expected ExpectStatus 200, got: %d", probe.ExpectStatus)
+	}
+}
+
+func TestParseConfig_InvalidProbes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"with status", []string{"--status", "SUCCESS", "--wait-file", "/run/app.ready"}, "cannot be combined with --status"},
+		{"bad url", []string{"--wait-http", "localhost:8080/health"}, "--wait-http must be an http or https URL"},
+		{"bad status", []string{"--wait-http", "http://localhost/health", "--expect-status", "42"}, "--expect-status must be an HTTP status code"},
+		{"bad address", []string{"--wait-tcp", "localhost"}, "--wait-tcp must be host:port"},
+		{"zero interval", []string{"--wait-file", "/run/app.ready", "--interval", "0s"}, "--interval must be positive"},
+		{"negative timeout", []string{"--wait-file", "/run/app.ready", "--wait-timeout", "-1s"}, "--wait-timeout must not be negative"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			_, err := ParseConfig()
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_WaitCloudInit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"alone", []string{"--wait-cloud-init"}, ""},
+		{"with probe", []string{"--wait-cloud-init", "--wait-http", "http://localhost:8080/health"}, ""},
+		{"with exec", []string{"--wait-cloud-init", "--exec", "./install.sh"}, "--wait-cloud-init cannot be combined"},
+		{"with status", []string{"--wait-cloud-init", "--status", "SUCCESS"}, "--wait-cloud-init cannot be combined"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if !cfg.WaitCloudInit || cfg.CloudInitRoot != "/" {
+					t.Errorf("Expected cloud-init wait with root /, got: %+v", cfg)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Supervise(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"argv", []string{"--ready-http", "http://localhost:8080/health", "--", "nginx", "-g", "daemon off;"}, ""},
+		{"exec", []string{"--ready-tcp", "localhost:8080", "--exec", "nginx"}, ""},
+		{"no probes", []string{"--", "nginx"}, ""},
+		{"no command", []string{"--ready-file", "/tmp/ready"}, "supervise requires a command"},
+		{"with status", []string{"--status", "SUCCESS", "--exec", "nginx"}, "supervise cannot be combined"},
+		{"with exec timeout", []string{"--exec-timeout", "1m", "--", "nginx"}, "supervise cannot be combined"},
+		{"with heartbeat", []string{"--heartbeat", "30s", "--", "nginx"}, "supervise cannot be combined"},
+		{"with wait probe", []string{"--wait-file", "/tmp/ready", "--", "nginx"}, "supervise uses --ready-* probes instead of --wait-*"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws", "supervise",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if !cfg.Supervise {
+					t.Errorf("Expected supervise mode, got: %+v", cfg)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_ReadyProbes(t *testing.T) {
+	parse := func(args ...string) (*Config, error) {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		flag.CommandLine.SetOutput(io.Discard)
+		os.Args = append([]string{"tcsignal-aws"}, args...)
+		return ParseConfig()
+	}
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	// The service's --ready-* probes become its readiness probes
+	cfg, err := parse("supervise", "--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue", "--id", "test-signal-123",
+		"--ready-http", "http://localhost:8080/health", "--ready-cmd", "pg_isready", "--", "nginx")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.WaitHTTP != "http://localhost:8080/health" || cfg.WaitCmd != "pg_isready" {
+		t.Errorf("Expected --ready-* probes to be used, got: %+v", cfg)
+	}
+
+	// --ready-* only apply to a supervised service
+	_, err = parse("--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue", "--id", "test-signal-123",
+		"--exec", "./install.sh", "--ready-http", "http://localhost:8080/health")
+	if err == nil || !strings.Contains(err.Error(), "require supervise") {
+		t.Errorf("Expected --ready-* to require supervise, got: %v", err)
+	}
+}
+
+func TestParseConfig_OutputPatterns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"both", []string{"--exec", "./install.sh", "--success-pattern", "^Done", "--failure-pattern", "^ERROR:", "--kill-on-failure-pattern"}, ""},
+		{"invalid regex", []string{"--exec", "./install.sh", "--failure-pattern", "(ERROR"}, "missing closing )"},
+		{"without command", []string{"--status", "SUCCESS", "--failure-pattern", "ERROR"}, "require a command"},
+		{"kill without failure pattern", []string{"--exec", "./install.sh", "--kill-on-failure-pattern"}, "--kill-on-failure-pattern requires --failure-pattern"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if cfg.SuccessPattern.String() != "^Done" || cfg.FailurePattern.String() != "^ERROR:" || !cfg.KillOnFailure {
+					t.Errorf("Expected patterns to be parsed, got: %+v", cfg)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_ExecRetries(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"retries", []string{"--exec", "./install.sh", "--exec-retries", "3", "--exec-retry-delay", "10s", "--exec-retry-backoff", "1.5"}, ""},
+		{"negative retries", []string{"--exec", "./install.sh", "--exec-retries", "-1"}, "must not be negative"},
+		{"backoff below 1", []string{"--exec", "./install.sh", "--exec-retries", "3", "--exec-retry-backoff", "0.5"}, "--exec-retry-backoff must be at least 1"},
+		{"without command", []string{"--status", "SUCCESS", "--exec-retries", "3"}, "--exec-retries requires a command"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if cfg.ExecRetries != 3 || cfg.ExecRetryDelay != 10*time.Second || cfg.ExecRetryBackoff != 1.5 {
+					t.Errorf("Expected retry options to be parsed, got: %+v", cfg)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_ExecLimits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"limits", []string{"--exec", "./install.sh", "--exec-nice", "10", "--exec-ionice", "best-effort:7", "--exec-rlimit", "nofile=1024,nproc=100", "--exec-rlimit", "nofile=4096:65536,core=unlimited", "--exec-oom-score-adj", "500"}, ""},
+		{"unknown I/O class", []string{"--exec", "./install.sh", "--exec-ionice", "low"}, "unknown I/O class"},
+		{"I/O level out of range", []string{"--exec", "./install.sh", "--exec-ionice", "best-effort:8"}, "invalid I/O level"},
+		{"idle I/O level", []string{"--exec", "./install.sh", "--exec-ionice", "idle:3"}, "the idle I/O class has no level"},
+		{"unknown resource", []string{"--exec", "./install.sh", "--exec-rlimit", "files=1024"}, "unknown resource"},
+		{"soft above hard", []string{"--exec", "./install.sh", "--exec-rlimit", "nofile=2048:1024"}, "must not exceed the hard limit"},
+		{"nice out of range", []string{"--exec", "./install.sh", "--exec-nice", "20"}, "--exec-nice must be between -20 and 19"},
+		{"oom score out of range", []string{"--exec", "./install.sh", "--exec-oom-score-adj", "-1001"}, "--exec-oom-score-adj must be between -1000 and 1000"},
+		{"without command", []string{"--status", "SUCCESS", "--exec-nice", "5"}, "require a command"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				limits := cfg.ExecLimits()
+				expected := []Rlimit{
+					{Resource: "nproc", Soft: 100, Hard: 100},
+					{Resource: "nofile", Soft: 4096, Hard: 65536},
+					{Resource: "core", Soft: RlimitInfinity, Hard: RlimitInfinity},
+				}
+				if limits.Nice != 10 || !reflect.DeepEqual(limits.Rlimits, expected) || limits.OOMScoreAdj == nil || *limits.OOMScoreAdj != 500 ||
+					limits.IOPriority == nil || *limits.IOPriority != (IOPriority{Class: IOClassBestEffort, Level: 7}) {
+					t.Errorf("Expected limits to be parsed, got: %+v", limits)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_ExecStdin(t *testing.T) {
+	answers := filepath.Join(t.TempDir(), "answers.txt")
+	if err := os.WriteFile(answers, []byte("yes\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write answers file: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"tty and file", []string{"--exec", "./install.sh", "--exec-tty", "--exec-stdin", answers}, ""},
+		{"inherit", []string{"--exec", "./install.sh", "--exec-stdin", "inherit"}, ""},
+		{"missing file", []string{"--exec", "./install.sh", "--exec-stdin", answers + ".missing"}, "--exec-stdin:"},
+		{"tty without command", []string{"--wait-file", "/tmp/ready", "--exec-tty"}, "--exec-tty and --exec-stdin require a command"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			_, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestConfig_OpenExecStdin(t *testing.T) {
+	answers := filepath.Join(t.TempDir(), "answers.txt")
+	if err := os.WriteFile(answers, []byte("yes\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write answers file: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"default", Config{}, ""},
+		{"null", Config{ExecStdin: StdinNull, Supervise: true}, ""},
+		{"supervise default", Config{Supervise: true}, "inherit"},
+		{"inherit", Config{ExecStdin: StdinInherit}, "inherit"},
+		{"file", Config{ExecStdin: answers}, "yes\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdin, closeStdin, err := tc.cfg.OpenExecStdin()
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			defer closeStdin()
+
+			var got string
+			switch {
+			case stdin == nil:
+			case stdin == os.Stdin:
+				got = "inherit"
+			default:
+				data, err := io.ReadAll(stdin)
+				if err != nil {
+					t.Fatalf("Failed to read stdin: %v", err)
+				}
+				got = string(data)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected stdin %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Checks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"checks", []string{"--check", "disk=mountpoint -q /data", "--check", "agent=agent status", "--min-pass", "1", "--check-concurrency", "2", "--check-timeout", "30s"}, ""},
+		{"invalid check", []string{"--check", "mountpoint -q /data"}, "expected NAME=CMD"},
+		{"duplicate name", []string{"--check", "disk=true", "--check", "disk=false"}, "duplicate check name"},
+		{"min pass too high", []string{"--check", "disk=true", "--min-pass", "2"}, "--min-pass must be between 0 and the number of checks (1)"},
+		{"no concurrency", []string{"--check", "disk=true", "--check-concurrency", "0"}, "--check-concurrency must be at least 1"},
+		{"with status", []string{"--check", "disk=true", "--status", "SUCCESS"}, "--check cannot be combined"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset flag set for testing
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+
+			os.Args = append([]string{
+				"tcsignal-aws",
+				"--queue-url", "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+				"--id", "test-signal-123",
+			}, tc.args...)
+
+			cfg, err := ParseConfig()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				expected := []Check{{Name: "disk", Command: "mountpoint -q /data"}, {Name: "agent", Command: "agent status"}}
+				if !reflect.DeepEqual(cfg.Checks, expected) || cfg.MinPass != 1 || cfg.CheckConcurrency != 2 || cfg.CheckTimeout != 30*time.Second {
+					t.Errorf("Expected checks to be parsed, got: %+v", cfg)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
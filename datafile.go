@@ -0,0 +1,81 @@
+package signal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MaxDataFileSize is the largest data file read, leaving room in the SQS
+// message for the rest of the signal
+const MaxDataFileSize = 64 * 1024
+
+// DataFile is a file a command can write a JSON object to in order to add
+// structured data to its signal, e.g. installed versions or generated
+// endpoints
+type DataFile struct {
+	Path string
+}
+
+// CreateDataFile creates an empty data file in the temporary directory. When
+// the command runs as another user or group, the file is handed over to
+// them so the command can write it.
+func CreateDataFile(userName, groupName string) (*DataFile, error) {
+	f, err := os.CreateTemp("", "tcsignal-data-*.json")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if userName != "" || groupName != "" {
+		if err := chownFile(path, userName, groupName); err != nil {
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to give %s to the command's user: %w", path, err)
+		}
+	}
+
+	return &DataFile{Path: path}, nil
+}
+
+// Reset empties the file so a rerun starts afresh
+func (f *DataFile) Reset() error {
+	return os.Truncate(f.Path, 0)
+}
+
+// Read returns the JSON object in the file, or nil if the command did not
+// write anything. A file larger than MaxDataFileSize is an error.
+func (f *DataFile) Read() (map[string]any, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, MaxDataFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MaxDataFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", f.Path, MaxDataFileSize)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("%s must contain a JSON object: %w", f.Path, err)
+	}
+	return data, nil
+}
+
+// Remove deletes the file
+func (f *DataFile) Remove() error {
+	return os.Remove(f.Path)
+}
@@ -0,0 +1,82 @@
+package signal
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDataFile(t *testing.T) {
+	f, err := CreateDataFile("", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer f.Remove()
+
+	// Nothing written
+	data, err := f.Read()
+	if err != nil || data != nil {
+		t.Errorf("Expected no data, got: %v, %v", data, err)
+	}
+
+	if err := os.WriteFile(f.Path, []byte(`{"version": "1.4.2", "ports": [80, 443]}`+"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+
+	data, err = f.Read()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := map[string]any{"version": "1.4.2", "ports": []any{80.0, 443.0}}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v, got: %v", expected, data)
+	}
+
+	if err := f.Reset(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data, err := f.Read(); err != nil || data != nil {
+		t.Errorf("Expected no data after reset, got: %v, %v", data, err)
+	}
+
+	if err := f.Remove(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := os.Stat(f.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected data file to be removed, got: %v", err)
+	}
+}
+
+func TestDataFile_Invalid(t *testing.T) {
+	f, err := CreateDataFile("", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer f.Remove()
+
+	for _, content := range []string{"version=1.4.2", `["not", "an", "object"]`} {
+		if err := os.WriteFile(f.Path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write data file: %v", err)
+		}
+		if _, err := f.Read(); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestDataFile_TooLarge(t *testing.T) {
+	f, err := CreateDataFile("", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer f.Remove()
+
+	content := `{"log": "` + strings.Repeat("x", MaxDataFileSize) + `"}`
+	if err := os.WriteFile(f.Path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	if _, err := f.Read(); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected a size error, got: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package signal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.uber.org/zap"
+)
+
+// DryRunPublisher prints the SendMessage request that SQSPublisher would
+// send as JSON instead of sending it
+type DryRunPublisher struct {
+	Logger Logger
+	Out    io.Writer
+}
+
+func NewDryRunPublisher(logger Logger, out io.Writer) *DryRunPublisher {
+	return &DryRunPublisher{
+		Logger: logger,
+		Out:    out,
+	}
+}
+
+// dryRunRequest mirrors the SendMessage API request shape
+type dryRunRequest struct {
+	QueueURL          string                     `json:"QueueUrl"`
+	Region            string                     `json:"Region,omitempty"`
+	MessageBody       string                     `json:"MessageBody"`
+	MessageAttributes map[string]dryRunAttribute `json:"MessageAttributes"`
+}
+
+type dryRunAttribute struct {
+	DataType    string `json:"DataType"`
+	StringValue string `json:"StringValue"`
+}
+
+func (p *DryRunPublisher) Publish(ctx context.Context, input PublishInput) error {
+	sqsInput, err := buildSendMessageInput(input)
+	if err != nil {
+		return err
+	}
+
+	request := dryRunRequest{
+		QueueURL:          aws.ToString(sqsInput.QueueUrl),
+		Region:            input.Region,
+		MessageBody:       aws.ToString(sqsInput.MessageBody),
+		MessageAttributes: make(map[string]dryRunAttribute, len(sqsInput.MessageAttributes)),
+	}
+	for name, attr := range sqsInput.MessageAttributes {
+		request.MessageAttributes[name] = dryRunAttribute{
+			DataType:    aws.ToString(attr.DataType),
+			StringValue: aws.ToString(attr.StringValue),
+		}
+	}
+
+	encoder := json.NewEncoder(p.Out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(request); err != nil {
+		return err
+	}
+
+	p.Logger.Info("Dry run: SQS message not sent",
+		zap.String("signal_id", input.SignalID),
+		zap.String("instance_id", input.InstanceID),
+		zap.Stringer("status", input.Status))
+
+	return nil
+}
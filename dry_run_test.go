@@ -0,0 +1,58 @@
+package signal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestDryRunPublisher_RendersRequest(t *testing.T) {
+	var out bytes.Buffer
+	publisher := NewDryRunPublisher(createTestLogger(), &out)
+
+	input := PublishInput{
+		QueueURL:   "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue",
+		SignalID:   "test-signal-123",
+		InstanceID: "i-1234567890abcdef0",
+		Status:     "SUCCESS",
+		Region:     "us-east-1",
+	}
+
+	if err := publisher.Publish(context.Background(), input); err != nil {
+		t.Fatalf("Expected no error from dry run, got: %v", err)
+	}
+
+	var request dryRunRequest
+	if err := json.Unmarshal(out.Bytes(), &request); err != nil {
+		t.Fatalf("Expected JSON output, got: %v\n%s", err, out.String())
+	}
+
+	if request.QueueURL != input.QueueURL {
+		t.Errorf("Expected QueueUrl %s, got: %s", input.QueueURL, request.QueueURL)
+	}
+
+	if request.Region != "us-east-1" {
+		t.Errorf("Expected Region us-east-1, got: %s", request.Region)
+	}
+
+	if request.MessageBody != "tcsignal-aws message" {
+		t.Errorf("Expected default message body, got: %s", request.MessageBody)
+	}
+
+	expected := map[string]string{
+		"signal_id":   input.SignalID,
+		"instance_id": input.InstanceID,
+		"status":      string(input.Status),
+	}
+	for name, value := range expected {
+		attr, ok := request.MessageAttributes[name]
+		if !ok {
+			t.Errorf("Expected attribute %s to be rendered", name)
+			continue
+		}
+		if attr.DataType != "String" || attr.StringValue != value {
+			t.Errorf("Expected attribute %s to be String %q, got: %+v", name, value, attr)
+		}
+	}
+}
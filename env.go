@@ -0,0 +1,66 @@
+package signal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envNamePattern matches environment variable names accepted in
+// --exec-env and --exec-env-file
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envListValue collects repeated KEY=VALUE flags
+type envListValue []string
+
+func (v *envListValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *envListValue) Set(s string) error {
+	name, _, ok := strings.Cut(s, "=")
+	if !ok || !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable %q (expected KEY=VALUE)", s)
+	}
+	*v = append(*v, s)
+	return nil
+}
+
+// LoadEnvFile reads KEY=VALUE lines from a file. Blank lines and lines
+// starting with # are skipped, an "export " prefix is ignored, and values
+// may be wrapped in single or double quotes.
+func LoadEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%s:%d: invalid line (expected KEY=VALUE)", path, lineNum)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, name+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
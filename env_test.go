@@ -0,0 +1,56 @@
+package signal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	content := `# application settings
+APP_ENV=production
+
+export DB_HOST = db.internal
+GREETING="hello world"
+QUOTED='single'
+EMPTY=
+URL=https://example.com/?a=b
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	env, err := LoadEnvFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []string{
+		"APP_ENV=production",
+		"DB_HOST=db.internal",
+		"GREETING=hello world",
+		"QUOTED=single",
+		"EMPTY=",
+		"URL=https://example.com/?a=b",
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %q, got: %q", expected, env)
+	}
+}
+
+func TestLoadEnvFile_Invalid(t *testing.T) {
+	for _, content := range []string{"NO_EQUALS\n", "1BAD=value\n", "BAD-NAME=value\n"} {
+		path := filepath.Join(t.TempDir(), "app.env")
+		if err := os.WriteFile(path, []byte("OK=1\n"+content), 0o600); err != nil {
+			t.Fatalf("Failed to write env file: %v", err)
+		}
+
+		_, err := LoadEnvFile(path)
+		if err == nil || !strings.Contains(err.Error(), ":2: invalid line") {
+			t.Errorf("Expected invalid line error for %q, got: %v", content, err)
+		}
+	}
+}
@@ -1,39 +1,422 @@
 package signal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	ossignal "os/signal"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// ErrExecTimeout is returned when a command exceeds its execution timeout
+var ErrExecTimeout = errors.New("command timed out")
+
+// ExecSpec describes a command to run
+type ExecSpec struct {
+	// Command is a shell command line, run with sh -c
+	Command string
+	// Args is an argv run directly without a shell, with Args[0] looked up
+	// in PATH; used when Command is empty
+	Args []string
+	// Env is the child environment; nil inherits the current environment
+	Env []string
+	// Dir is the working directory; empty uses the current directory
+	Dir string
+	// User and Group run the command as another user or group, given by
+	// name or numeric ID. Not supported on Windows.
+	User  string
+	Group string
+
+	Stdin  io.Reader
+	Stdout io.Writer // nil writes to os.Stdout
+	Stderr io.Writer // nil writes to os.Stderr
+
+	// TTY runs the command under a pseudo-terminal that is its controlling
+	// terminal, stdin, stdout and stderr. The terminal's output is written
+	// to Stdout, and Stdin is typed into it followed by end-of-file. Linux
+	// only.
+	TTY bool
+}
+
+// String returns a printable form of the command for logs and signals
+func (s ExecSpec) String() string {
+	if s.Command != "" || len(s.Args) == 0 {
+		return s.Command
+	}
+
+	quoted := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ExecResult describes how a command finished
+type ExecResult struct {
+	// Started reports whether the command was started. A command that could
+	// not be started has no exit code, signal or usage.
+	Started bool
+	// ExitCode is the command's exit code, or -1 if it did not exit normally
+	ExitCode int
+	// Signal is the name of the signal that terminated the command, if any
+	Signal string
+	// CoreDumped reports whether the terminating signal dumped core
+	CoreDumped bool
+	// Duration is the wall time the command ran for
+	Duration time.Duration
+	// Usage is the resources used by the command and its waited-for
+	// children
+	Usage ResourceUsage
+	// Interrupted is the name of the first signal forwarded to the command
+	Interrupted string
+}
+
+// ResourceUsage is the resource usage of a finished command
+type ResourceUsage struct {
+	// MaxRSS is the peak resident set size in bytes, or 0 if unknown
+	MaxRSS     int64
+	UserTime   time.Duration
+	SystemTime time.Duration
+}
+
 type Executor interface {
+	Run(ctx context.Context, spec ExecSpec) (ExecResult, error)
+}
+
+// LegacyExecutor is the original string-based executor signature
+type LegacyExecutor interface {
 	Run(cmdLine string) (exitCode int, err error)
 }
 
+// CommandLineExecutor adapts an Executor to the LegacyExecutor signature
+// for callers that need neither cancellation nor rich results
+type CommandLineExecutor struct {
+	Executor Executor
+}
+
+func (a CommandLineExecutor) Run(cmdLine string) (int, error) {
+	result, err := a.Executor.Run(context.Background(), ExecSpec{Command: cmdLine})
+	return result.ExitCode, err
+}
+
+// FromLegacyExecutor adapts a LegacyExecutor to the Executor interface.
+// Only shell command lines are supported and the context is ignored.
+func FromLegacyExecutor(legacy LegacyExecutor) Executor {
+	return legacyExecutor{legacy: legacy}
+}
+
+type legacyExecutor struct {
+	legacy LegacyExecutor
+}
+
+func (a legacyExecutor) Run(ctx context.Context, spec ExecSpec) (ExecResult, error) {
+	if spec.Command == "" {
+		return ExecResult{ExitCode: -1}, fmt.Errorf("legacy executor only supports shell command lines")
+	}
+
+	start := time.Now()
+	exitCode, err := a.legacy.Run(spec.Command)
+	return ExecResult{Started: err == nil, ExitCode: exitCode, Duration: time.Since(start)}, err
+}
+
 type DefaultExecutor struct {
 	Logger Logger
+	// Timeout bounds how long the command may run. Zero means no limit.
+	Timeout time.Duration
+	// KillGrace is how long to wait after SIGTERM before sending SIGKILL
+	KillGrace time.Duration
+	// ForwardSignals are relayed to the command's process group while it
+	// runs instead of terminating this process
+	ForwardSignals []os.Signal
+	// RelaySignals are relayed to the command's process group without
+	// starting the KillGrace timer, e.g. SIGHUP to reload a service
+	RelaySignals []os.Signal
+	// Reaper, if set, is reaping orphaned processes while this process runs
+	// as PID 1. Commands are registered with it so it leaves them to Run.
+	Reaper *Reaper
+	// Limits are applied to the command before it is executed, by running
+	// this executable as a shim that applies them. This process keeps its
+	// own priority and limits so it can still publish a signal when the
+	// command exhausts the instance. Linux only.
+	Limits ResourceLimits
 }
 
 func NewDefaultExecutor(logger Logger) *DefaultExecutor {
 	return &DefaultExecutor{
-		Logger: logger,
+		Logger:    logger,
+		KillGrace: 10 * time.Second,
+	}
+}
+
+// Run starts the command and waits for it to finish. When ctx is cancelled
+// or Timeout elapses, the command's process group is terminated. Signals in
+// ForwardSignals are relayed to the process group, which is killed if it
+// has not exited within KillGrace.
+func (e *DefaultExecutor) Run(ctx context.Context, spec ExecSpec) (ExecResult, error) {
+	e.Logger.Debug("Executing command", zap.Stringer("command", spec))
+
+	cmd, err := e.command(spec)
+	if err != nil {
+		return ExecResult{ExitCode: -1}, err
+	}
+
+	// Run the command in its own process group so cancellation reaches
+	// every process it spawned, not just the shell
+	setProcessGroup(cmd)
+
+	// A process the command started in the background may hold its output
+	// open long after it exits, so stop waiting for output after a while
+	cmd.WaitDelay = e.outputWait()
+
+	var shim *limitsShim
+	if !e.Limits.IsZero() {
+		if shim, err = wrapLimits(cmd, e.Limits); err != nil {
+			return ExecResult{ExitCode: -1}, fmt.Errorf("applying resource limits: %w", err)
+		}
+	}
+
+	var tty *terminal
+	if spec.TTY {
+		if tty, err = attachTerminal(cmd, spec.Stdin); err != nil {
+			return ExecResult{ExitCode: -1}, err
+		}
+	}
+
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, e.Timeout, fmt.Errorf("%w after %s", ErrExecTimeout, e.Timeout))
+		defer cancel()
+	}
+
+	// Forward termination signals received by this process to the command
+	var signals chan os.Signal
+	if len(e.ForwardSignals) > 0 {
+		signals = make(chan os.Signal, 1)
+		ossignal.Notify(signals, e.ForwardSignals...)
+		defer ossignal.Stop(signals)
+	}
+	var relay chan os.Signal
+	if len(e.RelaySignals) > 0 {
+		relay = make(chan os.Signal, 1)
+		ossignal.Notify(relay, e.RelaySignals...)
+		defer ossignal.Stop(relay)
 	}
+
+	start := time.Now()
+	if err := e.start(cmd); err != nil {
+		if shim != nil {
+			shim.close()
+		}
+		if tty != nil {
+			tty.close()
+		}
+		return ExecResult{ExitCode: -1}, err
+	}
+	if tty != nil {
+		tty.start()
+	}
+
+	// The command is only executed once its limits are in place
+	if shim != nil {
+		if err := shim.started(); err != nil {
+			_ = e.wait(cmd)
+			if tty != nil {
+				tty.wait(e.outputWait())
+			}
+			return ExecResult{ExitCode: -1}, err
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := e.wait(cmd)
+		// Output written to the terminal just before exiting is still
+		// buffered in it
+		if tty != nil {
+			tty.wait(e.outputWait())
+		}
+		done <- err
+	}()
+
+	var (
+		cancelled   = ctx.Done()
+		interrupted string
+		kill        <-chan time.Time
+	)
+	for {
+		select {
+		case err := <-done:
+			result, err := e.result(cmd, start, err)
+			result.Interrupted = interrupted
+			if err == nil && cancelled == nil {
+				err = context.Cause(ctx)
+			}
+			return result, err
+
+		case <-cancelled:
+			cancelled = nil
+			e.Logger.Warn("Command cancelled, terminating process group",
+				zap.Stringer("command", spec),
+				zap.NamedError("cause", context.Cause(ctx)))
+			if err := terminateProcessGroup(cmd); err != nil {
+				e.Logger.Debug("Failed to terminate process group", zap.Error(err))
+			}
+			if kill == nil {
+				kill = time.After(e.KillGrace)
+			}
+
+		case sig := <-signals:
+			e.Logger.Warn("Forwarding signal to command",
+				zap.Stringer("command", spec),
+				zap.String("signal", osSignalName(sig)))
+			if err := forwardSignal(cmd, sig); err != nil {
+				e.Logger.Debug("Failed to forward signal", zap.Error(err))
+			}
+			if interrupted == "" {
+				interrupted = osSignalName(sig)
+			}
+			if kill == nil {
+				kill = time.After(e.KillGrace)
+			}
+
+		case sig := <-relay:
+			e.Logger.Debug("Relaying signal to command",
+				zap.Stringer("command", spec),
+				zap.String("signal", osSignalName(sig)))
+			if err := forwardSignal(cmd, sig); err != nil {
+				e.Logger.Debug("Failed to relay signal", zap.Error(err))
+			}
+
+		case <-kill:
+			kill = nil
+			e.Logger.Warn("Command did not exit after grace period, killing process group",
+				zap.Stringer("command", spec),
+				zap.Duration("kill_grace", e.KillGrace))
+			if err := killProcessGroup(cmd); err != nil {
+				e.Logger.Debug("Failed to kill process group", zap.Error(err))
+			}
+		}
+	}
+}
+
+// SignalName returns the conventional name of sig, such as SIGTERM, as
+// reported in ExecResult.Interrupted
+func SignalName(sig os.Signal) string {
+	return osSignalName(sig)
+}
+
+// minOutputWait is the least time output is waited for after the command
+// exits, so that output still buffered when it exits is not lost
+const minOutputWait = 100 * time.Millisecond
+
+// outputWait is how long to wait for the command's output to be closed
+// after it exits
+func (e *DefaultExecutor) outputWait() time.Duration {
+	return max(e.KillGrace, minOutputWait)
+}
+
+// ShellExitCode returns the exit code a shell would report for the
+// command: its exit code, or 128 plus the signal number if a signal
+// terminated it
+func (r ExecResult) ShellExitCode() int {
+	if r.Signal != "" {
+		if n := signalNumber(r.Signal); n > 0 {
+			return 128 + n
+		}
+	}
+	if r.ExitCode < 0 {
+		return 1
+	}
+	return r.ExitCode
+}
+
+// start starts cmd, registering it with the Reaper if there is one
+func (e *DefaultExecutor) start(cmd *exec.Cmd) error {
+	if e.Reaper != nil {
+		return e.Reaper.startCommand(cmd)
+	}
+	return cmd.Start()
 }
 
-func (e *DefaultExecutor) Run(cmdLine string) (int, error) {
-	e.Logger.Debug("Executing command", zap.String("command", cmdLine))
+// wait waits for cmd, then lets the Reaper reap orphans again
+func (e *DefaultExecutor) wait(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	if e.Reaper != nil {
+		e.Reaper.release(cmd.Process.Pid)
+	}
+	return err
+}
 
-	cmd := exec.Command("sh", "-c", cmdLine)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// command builds the exec.Cmd for a spec
+func (e *DefaultExecutor) command(spec ExecSpec) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	switch {
+	case spec.Command != "":
+		cmd = exec.Command("sh", "-c", spec.Command)
+	case len(spec.Args) > 0:
+		cmd = exec.Command(spec.Args[0], spec.Args[1:]...)
+	default:
+		return nil, fmt.Errorf("no command to execute")
+	}
 
-	if err := cmd.Run(); err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			return exitError.ExitCode(), nil
+	cmd.Env = spec.Env
+	cmd.Dir = spec.Dir
+	if spec.User != "" || spec.Group != "" {
+		if err := setCredential(cmd, spec.User, spec.Group); err != nil {
+			return nil, err
+		}
+	}
+	cmd.Stdin = spec.Stdin
+	cmd.Stdout = spec.Stdout
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	cmd.Stderr = spec.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	return cmd, nil
+}
+
+// result converts the outcome of cmd.Wait into an ExecResult
+func (e *DefaultExecutor) result(cmd *exec.Cmd, start time.Time, waitErr error) (ExecResult, error) {
+	result := ExecResult{
+		ExitCode: -1,
+		Duration: time.Since(start),
+	}
+
+	if state := cmd.ProcessState; state != nil {
+		result.Started = true
+		result.ExitCode = state.ExitCode()
+		result.Signal, result.CoreDumped = terminatingSignal(state)
+		result.Usage = ResourceUsage{
+			MaxRSS:     maxRSS(state),
+			UserTime:   state.UserTime(),
+			SystemTime: state.SystemTime(),
+		}
+	}
+
+	if errors.Is(waitErr, exec.ErrWaitDelay) {
+		e.Logger.Warn("Command exited but a process it started still holds its output open; no longer capturing it",
+			zap.Duration("output_wait", e.outputWait()))
+		waitErr = nil
+	}
+	if waitErr != nil {
+		if _, ok := waitErr.(*exec.ExitError); !ok {
+			return result, waitErr
 		}
-		return -1, err
 	}
 
-	return 0, nil
+	return result, nil
 }
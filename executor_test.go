@@ -1,8 +1,13 @@
 package signal
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Helper function to create a test logger
@@ -15,7 +20,8 @@ func TestDefaultExecutor_Success(t *testing.T) {
 	executor := NewDefaultExecutor(createTestLogger())
 
 	// Test with success.sh fixture
-	exitCode, err := executor.Run("./test/fixtures/success.sh")
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "./test/fixtures/success.sh"})
+	exitCode := result.ExitCode
 	if err != nil {
 		t.Fatalf("Expected no error for success.sh, got: %v", err)
 	}
@@ -29,7 +35,8 @@ func TestDefaultExecutor_Failure(t *testing.T) {
 	executor := NewDefaultExecutor(createTestLogger())
 
 	// Test with fail.sh fixture
-	exitCode, err := executor.Run("./test/fixtures/fail.sh")
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "./test/fixtures/fail.sh"})
+	exitCode := result.ExitCode
 	if err != nil {
 		t.Fatalf("Expected no error executing fail.sh, got: %v", err)
 	}
@@ -43,7 +50,8 @@ func TestDefaultExecutor_InvalidCommand(t *testing.T) {
 	executor := NewDefaultExecutor(createTestLogger())
 
 	// Test with non-existent command
-	exitCode, err := executor.Run("this-command-does-not-exist-12345")
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "this-command-does-not-exist-12345"})
+	exitCode := result.ExitCode
 
 	// sh -c will run but the command inside will fail with exit code 127 (command not found)
 	if err != nil {
@@ -60,7 +68,8 @@ func TestDefaultExecutor_Verbose(t *testing.T) {
 	// Test that verbose mode doesn't break execution
 	executor := NewDefaultExecutor(createTestLogger())
 
-	exitCode, err := executor.Run("echo 'verbose test'")
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "echo 'verbose test'"})
+	exitCode := result.ExitCode
 	if err != nil {
 		t.Fatalf("Expected no error with verbose mode, got: %v", err)
 	}
@@ -86,7 +95,8 @@ func TestDefaultExecutor_ExitCodeHandling(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			exitCode, err := executor.Run(tc.command)
+			result, err := executor.Run(context.Background(), ExecSpec{Command: tc.command})
+			exitCode := result.ExitCode
 			if err != nil {
 				t.Fatalf("Expected no error for '%s', got: %v", tc.command, err)
 			}
@@ -99,11 +109,229 @@ func TestDefaultExecutor_ExitCodeHandling(t *testing.T) {
 	}
 }
 
+func TestDefaultExecutor_Timeout(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Timeout = 100 * time.Millisecond
+	executor.KillGrace = time.Second
+
+	start := time.Now()
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "sleep 10"})
+	exitCode := result.ExitCode
+	if !errors.Is(err, ErrExecTimeout) {
+		t.Fatalf("Expected ErrExecTimeout, got: %v", err)
+	}
+
+	if exitCode != -1 {
+		t.Errorf("Expected exit code -1 on timeout, got: %d", exitCode)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to be terminated promptly, took: %v", elapsed)
+	}
+}
+
+func TestDefaultExecutor_TimeoutEscalatesToKill(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Timeout = 100 * time.Millisecond
+	executor.KillGrace = 200 * time.Millisecond
+
+	// The shell and its background child both ignore SIGTERM
+	start := time.Now()
+	_, err := executor.Run(context.Background(), ExecSpec{Command: "trap '' TERM; sleep 10 & wait"})
+	if !errors.Is(err, ErrExecTimeout) {
+		t.Fatalf("Expected ErrExecTimeout, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected SIGKILL after the grace period, took: %v", elapsed)
+	}
+}
+
+func TestDefaultExecutor_NoTimeout(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Timeout = 5 * time.Second
+
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "exit 3"})
+	exitCode := result.ExitCode
+	if err != nil {
+		t.Fatalf("Expected no error for fast command, got: %v", err)
+	}
+
+	if exitCode != 3 {
+		t.Errorf("Expected exit code 3, got: %d", exitCode)
+	}
+}
+
+func TestDefaultExecutor_ContextCancel(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.KillGrace = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := executor.Run(ctx, ExecSpec{Command: "sleep 10"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+
+	if result.Signal != "SIGTERM" {
+		t.Errorf("Expected command to be terminated by SIGTERM, got: %q", result.Signal)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to be cancelled promptly, took: %v", elapsed)
+	}
+}
+
+func TestDefaultExecutor_SpecIO(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout, stderr bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "read line; echo \"$line from $GREETING in $(pwd)\"; echo oops >&2",
+		Env:     []string{"GREETING=spec"},
+		Dir:     "/",
+		Stdin:   strings.NewReader("hello\n"),
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Errorf("Expected exit code 0, got: %d", result.ExitCode)
+	}
+
+	if got := stdout.String(); got != "hello from spec in /\n" {
+		t.Errorf("Expected stdout to reflect stdin, env and dir, got: %q", got)
+	}
+
+	if got := stderr.String(); got != "oops\n" {
+		t.Errorf("Expected stderr to be captured, got: %q", got)
+	}
+}
+
+func TestDefaultExecutor_Args(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Args:   []string{"echo", "$HOME", "a b"},
+		Stdout: &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Errorf("Expected exit code 0, got: %d", result.ExitCode)
+	}
+
+	// Arguments are passed verbatim without shell expansion
+	if got := stdout.String(); got != "$HOME a b\n" {
+		t.Errorf("Expected arguments to be passed verbatim, got: %q", got)
+	}
+}
+
+func TestDefaultExecutor_ArgsNotFound(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	result, err := executor.Run(context.Background(), ExecSpec{Args: []string{"this-command-does-not-exist-12345"}})
+	if err == nil {
+		t.Fatal("Expected error for missing executable without a shell")
+	}
+
+	if result.ExitCode != -1 {
+		t.Errorf("Expected exit code -1, got: %d", result.ExitCode)
+	}
+}
+
+func TestExecSpec_String(t *testing.T) {
+	testCases := []struct {
+		spec     ExecSpec
+		expected string
+	}{
+		{ExecSpec{Command: "echo 'hi there'"}, "echo 'hi there'"},
+		{ExecSpec{Args: []string{"echo", "plain"}}, "echo plain"},
+		{ExecSpec{Args: []string{"echo", "hi there", ""}}, `echo "hi there" ""`},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.spec.String(); got != tc.expected {
+			t.Errorf("Expected %q, got: %q", tc.expected, got)
+		}
+	}
+}
+
+func TestDefaultExecutor_Signal(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "kill -KILL $$"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ExitCode != -1 {
+		t.Errorf("Expected exit code -1 for signalled command, got: %d", result.ExitCode)
+	}
+
+	if result.Signal != "SIGKILL" {
+		t.Errorf("Expected signal SIGKILL, got: %q", result.Signal)
+	}
+
+	if !result.Started || result.CoreDumped {
+		t.Errorf("Expected a started command without a core dump, got: %+v", result)
+	}
+
+	if result.Duration <= 0 {
+		t.Errorf("Expected a positive duration, got: %v", result.Duration)
+	}
+}
+
+func TestCommandLineExecutor(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetExitCode(4)
+
+	var legacy LegacyExecutor = CommandLineExecutor{Executor: mock}
+	exitCode, err := legacy.Run("exit 4")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if exitCode != 4 {
+		t.Errorf("Expected exit code 4, got: %d", exitCode)
+	}
+
+	if calls := mock.GetCalls(); len(calls) != 1 || calls[0] != "exit 4" {
+		t.Errorf("Expected command line to be passed through, got: %v", calls)
+	}
+}
+
+func TestFromLegacyExecutor(t *testing.T) {
+	executor := FromLegacyExecutor(CommandLineExecutor{Executor: NewDefaultExecutor(createTestLogger())})
+
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "exit 6"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ExitCode != 6 {
+		t.Errorf("Expected exit code 6, got: %d", result.ExitCode)
+	}
+
+	if _, err := executor.Run(context.Background(), ExecSpec{Args: []string{"true"}}); err == nil {
+		t.Error("Expected error for argv spec on legacy executor")
+	}
+}
+
 func TestMockExecutor_Basic(t *testing.T) {
 	mock := NewMockExecutor()
 
 	// Test default behavior (should return 0, nil)
-	exitCode, err := mock.Run("test-command")
+	result, err := mock.Run(context.Background(), ExecSpec{Command: "test-command"})
+	exitCode := result.ExitCode
 	if err != nil {
 		t.Errorf("Expected no error from mock, got: %v", err)
 	}
@@ -125,7 +353,8 @@ func TestMockExecutor_SetExitCode(t *testing.T) {
 	mock := NewMockExecutor()
 	mock.SetExitCode(42)
 
-	exitCode, err := mock.Run("test-command")
+	result, err := mock.Run(context.Background(), ExecSpec{Command: "test-command"})
+	exitCode := result.ExitCode
 	if err != nil {
 		t.Errorf("Expected no error from mock, got: %v", err)
 	}
@@ -139,7 +368,8 @@ func TestMockExecutor_SetError(t *testing.T) {
 	expectedErr := fmt.Errorf("mock error")
 	mock.SetError(expectedErr)
 
-	exitCode, err := mock.Run("test-command")
+	result, err := mock.Run(context.Background(), ExecSpec{Command: "test-command"})
+	exitCode := result.ExitCode
 	if err != expectedErr {
 		t.Errorf("Expected mock error, got: %v", err)
 	}
@@ -156,7 +386,8 @@ func TestMockExecutor_CustomResults(t *testing.T) {
 	mock.SetExitCode(1) // This should be overridden for special-command
 
 	// Test special command
-	exitCode, err := mock.Run("special-command")
+	result, err := mock.Run(context.Background(), ExecSpec{Command: "special-command"})
+	exitCode := result.ExitCode
 	if err != nil {
 		t.Errorf("Expected no error for special command, got: %v", err)
 	}
@@ -165,7 +396,8 @@ func TestMockExecutor_CustomResults(t *testing.T) {
 	}
 
 	// Test regular command (should use default)
-	exitCode, err = mock.Run("regular-command")
+	result, err = mock.Run(context.Background(), ExecSpec{Command: "regular-command"})
+	exitCode = result.ExitCode
 	if err != nil {
 		t.Errorf("Expected no error for regular command, got: %v", err)
 	}
@@ -186,7 +418,7 @@ func TestMockExecutor_ThreadSafety(t *testing.T) {
 	done := make(chan bool, 10)
 	for i := 0; i < 10; i++ {
 		go func(id int) {
-			mock.Run(fmt.Sprintf("command-%d", id))
+			mock.Run(context.Background(), ExecSpec{Command: fmt.Sprintf("command-%d", id)})
 			done <- true
 		}(i)
 	}
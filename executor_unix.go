@@ -0,0 +1,247 @@
+//go:build !windows
+
+package signal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// TerminationSignals are the signals forwarded to a running command when
+// this process is asked to stop
+var TerminationSignals = []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP}
+
+// StopSignals are the signals forwarded to a supervised service to stop it
+var StopSignals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+
+// ReloadSignals are relayed to a supervised service without stopping it
+var ReloadSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// setProcessGroup starts the command as the leader of a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// setCredential runs the command as userName and/or groupName. A user runs
+// with its primary and supplementary groups unless groupName overrides the
+// primary group, and HOME, USER and LOGNAME are set for the user.
+func setCredential(cmd *exec.Cmd, userName, groupName string) error {
+	cred := &syscall.Credential{
+		Uid:         uint32(os.Getuid()),
+		Gid:         uint32(os.Getgid()),
+		NoSetGroups: true,
+	}
+
+	if userName != "" {
+		u, err := lookupUser(userName)
+		if err != nil {
+			return err
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid uid %q for user %s", u.Uid, userName)
+		}
+		cred.Uid = uint32(uid)
+
+		if gid, err := strconv.ParseUint(u.Gid, 10, 32); err == nil {
+			cred.Gid = uint32(gid)
+		}
+
+		// Supplementary groups are best effort; users without an entry in
+		// the group database run with their primary group only
+		cred.NoSetGroups = false
+		if groupIDs, err := u.GroupIds(); err == nil {
+			for _, id := range groupIDs {
+				if gid, err := strconv.ParseUint(id, 10, 32); err == nil {
+					cred.Groups = append(cred.Groups, uint32(gid))
+				}
+			}
+		}
+
+		if u.Username != "" {
+			if cmd.Env == nil {
+				cmd.Env = os.Environ()
+			}
+			cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+		}
+	}
+
+	if groupName != "" {
+		gid, err := lookupGroup(groupName)
+		if err != nil {
+			return err
+		}
+		cred.Gid = gid
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = cred
+	return nil
+}
+
+// lookupUser finds a user by name or numeric ID. A numeric ID without an
+// entry in the user database is used as-is, with the same ID as its group
+// rather than this process's group, which may be root.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, convErr := strconv.ParseUint(name, 10, 32); convErr != nil {
+		return nil, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	if u, err := user.LookupId(name); err == nil {
+		return u, nil
+	}
+	return &user.User{Uid: name, Gid: name}, nil
+}
+
+// lookupGroup finds a group ID by name or numeric ID
+func lookupGroup(name string) (uint32, error) {
+	if gid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	gid, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gid %q for group %s", g.Gid, name)
+	}
+	return uint32(gid), nil
+}
+
+// chownFile gives path to userName and/or groupName. A file given to a group
+// only is made group-writable.
+func chownFile(path, userName, groupName string) error {
+	uid, gid := -1, -1
+	if userName != "" {
+		u, err := lookupUser(userName)
+		if err != nil {
+			return err
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return fmt.Errorf("invalid uid %q for user %s", u.Uid, userName)
+		}
+	}
+	if groupName != "" {
+		g, err := lookupGroup(groupName)
+		if err != nil {
+			return err
+		}
+		gid = int(g)
+	}
+
+	if err := os.Chown(path, uid, gid); err != nil {
+		return err
+	}
+	if userName == "" {
+		return os.Chmod(path, 0o660)
+	}
+	return nil
+}
+
+// terminateProcessGroup sends SIGTERM to the command's process group
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// killProcessGroup sends SIGKILL to the command's process group
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// forwardSignal relays sig to the command's process group
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) error {
+	sysSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+	return syscall.Kill(-cmd.Process.Pid, sysSig)
+}
+
+// signalNames maps the signals commonly seen terminating a command to their names
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGILL:  "SIGILL",
+	syscall.SIGTRAP: "SIGTRAP",
+	syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS:  "SIGBUS",
+	syscall.SIGFPE:  "SIGFPE",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGUSR1: "SIGUSR1",
+	syscall.SIGSEGV: "SIGSEGV",
+	syscall.SIGUSR2: "SIGUSR2",
+	syscall.SIGPIPE: "SIGPIPE",
+	syscall.SIGALRM: "SIGALRM",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGXCPU: "SIGXCPU",
+	syscall.SIGXFSZ: "SIGXFSZ",
+}
+
+// signalName returns the conventional name of a signal, e.g. SIGTERM
+func signalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
+
+// signalNumber returns the number of a signal named by signalName, or 0
+func signalNumber(name string) int {
+	for sig, n := range signalNames {
+		if n == name {
+			return int(sig)
+		}
+	}
+	var num int
+	if _, err := fmt.Sscanf(name, "signal %d", &num); err == nil {
+		return num
+	}
+	return 0
+}
+
+// osSignalName returns the conventional name of an os.Signal
+func osSignalName(sig os.Signal) string {
+	if sysSig, ok := sig.(syscall.Signal); ok {
+		return signalName(sysSig)
+	}
+	return sig.String()
+}
+
+// terminatingSignal returns the name of the signal that terminated the
+// process and whether it dumped core, or an empty string if it exited
+// normally
+func terminatingSignal(state *os.ProcessState) (string, bool) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return "", false
+	}
+	return signalName(status.Signal()), status.CoreDump()
+}
+
+// maxRSS returns the peak resident set size of the process in bytes
+func maxRSS(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// macOS reports bytes; Linux and the BSDs report kilobytes
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(usage.Maxrss)
+	}
+	return int64(usage.Maxrss) * 1024
+}
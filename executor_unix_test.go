@@ -0,0 +1,323 @@
+//go:build !windows
+
+package signal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDefaultExecutor_ForwardSignals(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.ForwardSignals = []os.Signal{syscall.SIGTERM}
+
+	// Deliver SIGTERM to this process once the command is running
+	time.AfterFunc(200*time.Millisecond, func() {
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	})
+
+	start := time.Now()
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "sleep 10"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Interrupted != "SIGTERM" {
+		t.Errorf("Expected Interrupted to be SIGTERM, got: %q", result.Interrupted)
+	}
+
+	if result.Signal != "SIGTERM" {
+		t.Errorf("Expected command to be terminated by SIGTERM, got: %q", result.Signal)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to stop promptly, took: %v", elapsed)
+	}
+}
+
+func TestDefaultExecutor_ForwardSignalsWaitsForChild(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.ForwardSignals = []os.Signal{syscall.SIGTERM}
+
+	time.AfterFunc(200*time.Millisecond, func() {
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	})
+
+	// The command handles SIGTERM itself and exits cleanly
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "trap 'echo cleaning up; exit 0' TERM; while true; do sleep 0.05; done",
+		Stdout:  &testWriter{t: t},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Interrupted != "SIGTERM" {
+		t.Errorf("Expected Interrupted to be SIGTERM, got: %q", result.Interrupted)
+	}
+
+	if result.ExitCode != 0 || result.Signal != "" {
+		t.Errorf("Expected command to exit cleanly after cleanup, got: %+v", result)
+	}
+}
+
+func TestDefaultExecutor_RelaySignals(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.RelaySignals = []os.Signal{syscall.SIGHUP}
+
+	time.AfterFunc(200*time.Millisecond, func() {
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	})
+
+	// The command reloads on SIGHUP and keeps running until it finishes
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "trap 'echo reloaded' HUP; for i in 1 2 3 4 5 6 7 8; do sleep 0.05; done",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Interrupted != "" || result.ExitCode != 0 || result.Signal != "" {
+		t.Errorf("Expected command to run to completion, got: %+v", result)
+	}
+
+	if !strings.Contains(stdout.String(), "reloaded") {
+		t.Errorf("Expected command to receive SIGHUP, got output: %q", stdout.String())
+	}
+}
+
+func TestExecResult_ShellExitCode(t *testing.T) {
+	testCases := []struct {
+		result   ExecResult
+		expected int
+	}{
+		{ExecResult{ExitCode: 0}, 0},
+		{ExecResult{ExitCode: 3}, 3},
+		{ExecResult{ExitCode: -1, Signal: "SIGTERM"}, 143},
+		{ExecResult{ExitCode: -1, Signal: "SIGKILL"}, 137},
+		{ExecResult{ExitCode: -1}, 1},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.result.ShellExitCode(); got != tc.expected {
+			t.Errorf("ShellExitCode(%+v) = %d, expected %d", tc.result, got, tc.expected)
+		}
+	}
+}
+
+// testWriter sends command output to the test log
+type testWriter struct {
+	t *testing.T
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.t.Log(string(p))
+	return len(p), nil
+}
+
+func TestDefaultExecutor_User(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("running as another user requires root")
+	}
+
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: `echo "$(id -u) $(id -g) $USER $HOME"`,
+		User:    "nobody",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Fatalf("Expected exit code 0, got: %d", result.ExitCode)
+	}
+
+	if got := strings.TrimSpace(stdout.String()); got != "65534 65534 nobody /nonexistent" {
+		t.Errorf("Expected command to run as nobody, got: %q", got)
+	}
+}
+
+func TestDefaultExecutor_Group(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing group requires root")
+	}
+
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout bytes.Buffer
+	_, err := executor.Run(context.Background(), ExecSpec{
+		Command: "id -u; id -g",
+		Group:   "12345",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got := strings.Fields(stdout.String()); len(got) != 2 || got[0] != "0" || got[1] != "12345" {
+		t.Errorf("Expected uid 0 and gid 12345, got: %q", stdout.String())
+	}
+}
+
+// Test that a UID without a user entry does not run with this process's
+// group
+func TestDefaultExecutor_NumericUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout bytes.Buffer
+	_, err := executor.Run(context.Background(), ExecSpec{
+		Command: "id -u; id -g; id -G",
+		User:    "54321",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got := strings.Fields(stdout.String()); len(got) != 3 || got[0] != "54321" || got[1] != "54321" || got[2] != "54321" {
+		t.Errorf("Expected uid, gid and groups 54321, got: %q", stdout.String())
+	}
+}
+
+func TestDefaultExecutor_UnknownUser(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	_, err := executor.Run(context.Background(), ExecSpec{
+		Command: "true",
+		User:    "no-such-user-tcsignal",
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown user") {
+		t.Errorf("Expected unknown user error, got: %v", err)
+	}
+
+	_, err = executor.Run(context.Background(), ExecSpec{
+		Command: "true",
+		Group:   "no-such-group-tcsignal",
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown group") {
+		t.Errorf("Expected unknown group error, got: %v", err)
+	}
+}
+
+func TestCreateDataFile_User(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("giving a file to another user requires root")
+	}
+
+	f, err := CreateDataFile("nobody", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer f.Remove()
+
+	// The command can write its data as that user
+	executor := NewDefaultExecutor(createTestLogger())
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: `echo '{"user":"nobody"}' > ` + f.Path,
+		User:    "nobody",
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Expected command to write the data file, got: %+v, %v", result, err)
+	}
+
+	if data, err := f.Read(); err != nil || data["user"] != "nobody" {
+		t.Errorf("Expected data written by nobody, got: %v, %v", data, err)
+	}
+}
+
+func TestDefaultExecutor_ResourceUsage(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	// Burn a little CPU so the usage is not all zero
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !result.Started || result.ExitCode != 0 || result.Signal != "" || result.CoreDumped {
+		t.Errorf("Expected a clean exit, got: %+v", result)
+	}
+
+	if result.Usage.MaxRSS <= 0 {
+		t.Errorf("Expected peak memory to be reported, got: %d", result.Usage.MaxRSS)
+	}
+
+	if result.Usage.UserTime+result.Usage.SystemTime <= 0 {
+		t.Errorf("Expected CPU time to be reported, got: %+v", result.Usage)
+	}
+}
+
+func TestDefaultExecutor_NotStarted(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	result, err := executor.Run(context.Background(), ExecSpec{Args: []string{"/nonexistent/command"}})
+	if err == nil {
+		t.Fatal("Expected an error for a missing command")
+	}
+
+	if result.Started || result.ExitCode != -1 {
+		t.Errorf("Expected a command that did not start, got: %+v", result)
+	}
+}
+
+func TestDefaultExecutor_BackgroundProcessHoldsOutput(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.KillGrace = 200 * time.Millisecond
+
+	// The background process keeps the output pipe open after the command
+	// exits; the command's result must not wait for it
+	var stdout bytes.Buffer
+	start := time.Now()
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "setsid sleep 5 & echo started",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the result soon after the command exited, took %v", elapsed)
+	}
+	if result.ExitCode != 0 || strings.TrimSpace(stdout.String()) != "started" {
+		t.Errorf("Expected exit code 0 and output \"started\", got %d and %q", result.ExitCode, stdout.String())
+	}
+}
+
+func TestDefaultExecutor_BackgroundProcessHoldsOutputTimeout(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Timeout = 200 * time.Millisecond
+	executor.KillGrace = 200 * time.Millisecond
+
+	// The background process escapes the process group, so terminating the
+	// command leaves it holding the output open
+	var stdout bytes.Buffer
+	start := time.Now()
+	_, err := executor.Run(context.Background(), ExecSpec{
+		Command: "setsid sleep 5 & sleep 100",
+		Stdout:  &stdout,
+	})
+	if !errors.Is(err, ErrExecTimeout) {
+		t.Fatalf("Expected a timeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected --exec-timeout to bound the command, took %v", elapsed)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build windows
+
+package signal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// TerminationSignals are the signals forwarded to a running command when
+// this process is asked to stop
+var TerminationSignals = []os.Signal{os.Interrupt}
+
+// StopSignals are the signals forwarded to a supervised service to stop it
+var StopSignals = []os.Signal{os.Interrupt}
+
+// ReloadSignals is empty; Windows cannot relay other signals
+var ReloadSignals []os.Signal
+
+// setProcessGroup is a no-op on Windows, which has no POSIX process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// setCredential is not supported on Windows
+func setCredential(cmd *exec.Cmd, userName, groupName string) error {
+	return fmt.Errorf("running a command as another user or group is not supported on Windows")
+}
+
+func chownFile(path, userName, groupName string) error {
+	return fmt.Errorf("changing file ownership is not supported on Windows")
+}
+
+// terminateProcessGroup kills the command; Windows has no SIGTERM
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcessGroup kills the command
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// terminatingSignal always returns an empty string; Windows has no signals
+func terminatingSignal(state *os.ProcessState) (string, bool) {
+	return "", false
+}
+
+// maxRSS returns 0; peak memory is not reported on Windows
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
+
+// forwardSignal kills the command; Windows cannot deliver other signals
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) error {
+	return cmd.Process.Kill()
+}
+
+// signalNumber always returns 0; Windows has no signals
+func signalNumber(name string) int {
+	return 0
+}
+
+// osSignalName returns the name of an os.Signal
+func osSignalName(sig os.Signal) string {
+	return sig.String()
+}
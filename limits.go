@@ -0,0 +1,165 @@
+package signal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// RlimitInfinity is the value of an unlimited resource limit
+const RlimitInfinity = math.MaxUint64
+
+// limitsShimEnabled records that RunLimitsShim has been called, so this
+// executable can be re-executed as a shim that applies resource limits
+var limitsShimEnabled atomic.Bool
+
+// RunLimitsShim must be called at the start of main by programs that run
+// commands with DefaultExecutor.Limits. The executor applies the limits by
+// re-executing the program as a shim, which applies them and then executes
+// the command in its place. When this process is such a shim, RunLimitsShim
+// does that and only returns, with true, if it failed; main must then exit
+// without doing anything else. Otherwise it returns false.
+func RunLimitsShim() bool {
+	limitsShimEnabled.Store(true)
+	return runLimitsShim()
+}
+
+// rlimitNames are the resources accepted by ParseRlimits
+var rlimitNames = []string{"as", "core", "cpu", "data", "fsize", "memlock", "nofile", "nproc", "stack"}
+
+// Rlimit is a soft and hard limit on a resource, named as in prlimit(1)
+// without the RLIMIT_ prefix, e.g. nofile
+type Rlimit struct {
+	Resource string
+	Soft     uint64
+	Hard     uint64
+}
+
+func (r Rlimit) String() string {
+	if r.Soft == r.Hard {
+		return r.Resource + "=" + rlimitValueString(r.Soft)
+	}
+	return r.Resource + "=" + rlimitValueString(r.Soft) + ":" + rlimitValueString(r.Hard)
+}
+
+// ResourceLimits lower the priority and limit the resources of a command,
+// leaving those of this process unchanged
+type ResourceLimits struct {
+	// Nice is the scheduling priority, from -20 (highest) to 19 (lowest).
+	// Zero leaves the priority unchanged.
+	Nice int
+	// Rlimits are applied to the command in order
+	Rlimits []Rlimit
+	// OOMScoreAdj, if set, is the command's oom_score_adj, from -1000
+	// (never killed) to 1000 (killed first)
+	OOMScoreAdj *int
+	// IOPriority, if set, is the command's I/O scheduling class and level
+	IOPriority *IOPriority
+}
+
+// IsZero reports whether no limits are set
+func (l ResourceLimits) IsZero() bool {
+	return l.Nice == 0 && len(l.Rlimits) == 0 && l.OOMScoreAdj == nil && l.IOPriority == nil
+}
+
+// I/O scheduling classes, named as in ionice(1)
+const (
+	IOClassRealtime   = "realtime"
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+// ioClasses maps the I/O scheduling classes to their IOPRIO_CLASS_* numbers
+var ioClasses = map[string]int{IOClassRealtime: 1, IOClassBestEffort: 2, IOClassIdle: 3}
+
+// IOPriority is an I/O scheduling class and a level within it, as set by
+// ionice(1)
+type IOPriority struct {
+	Class string
+	// Level is from 0 (highest) to 7 (lowest); the idle class has none
+	Level int
+}
+
+func (p IOPriority) String() string {
+	if p.Class == IOClassIdle {
+		return p.Class
+	}
+	return p.Class + ":" + strconv.Itoa(p.Level)
+}
+
+// ParseIOPriority parses CLASS[:LEVEL], e.g. idle or best-effort:7. The
+// level defaults to 4, as in ionice(1), and is not allowed for idle.
+func ParseIOPriority(s string) (IOPriority, error) {
+	class, levelStr, hasLevel := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	if _, ok := ioClasses[class]; !ok {
+		return IOPriority{}, fmt.Errorf("unknown I/O class %q (expected one of %s, %s, %s)", class, IOClassRealtime, IOClassBestEffort, IOClassIdle)
+	}
+
+	priority := IOPriority{Class: class}
+	switch {
+	case class == IOClassIdle && hasLevel:
+		return IOPriority{}, fmt.Errorf("the idle I/O class has no level")
+	case class == IOClassIdle:
+	case hasLevel:
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level < 0 || level > 7 {
+			return IOPriority{}, fmt.Errorf("invalid I/O level %q (expected 0 to 7)", levelStr)
+		}
+		priority.Level = level
+	default:
+		priority.Level = 4
+	}
+	return priority, nil
+}
+
+// ParseRlimits parses a comma-separated list of RESOURCE=LIMIT pairs, e.g.
+// nofile=65536,nproc=4096. A limit is a number, "unlimited", or SOFT:HARD
+// to set the soft and hard limits separately.
+func ParseRlimits(s string) ([]Rlimit, error) {
+	var limits []Rlimit
+	for _, pair := range strings.Split(s, ",") {
+		resource, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit %q (expected RESOURCE=LIMIT)", pair)
+		}
+		resource = strings.ToLower(strings.TrimSpace(resource))
+		if !slices.Contains(rlimitNames, resource) {
+			return nil, fmt.Errorf("unknown resource %q (expected one of %s)", resource, strings.Join(rlimitNames, ", "))
+		}
+
+		softStr, hardStr, separate := strings.Cut(strings.TrimSpace(value), ":")
+		soft, err := parseRlimitValue(softStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s limit %q", resource, value)
+		}
+		hard := soft
+		if separate {
+			if hard, err = parseRlimitValue(hardStr); err != nil {
+				return nil, fmt.Errorf("invalid %s limit %q", resource, value)
+			}
+			if soft > hard {
+				return nil, fmt.Errorf("%s soft limit must not exceed the hard limit", resource)
+			}
+		}
+
+		limits = append(limits, Rlimit{Resource: resource, Soft: soft, Hard: hard})
+	}
+	return limits, nil
+}
+
+func parseRlimitValue(s string) (uint64, error) {
+	if s == "unlimited" || s == "infinity" {
+		return RlimitInfinity, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func rlimitValueString(v uint64) string {
+	if v == RlimitInfinity {
+		return "unlimited"
+	}
+	return strconv.FormatUint(v, 10)
+}
@@ -0,0 +1,209 @@
+package signal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// rlimitResources maps the resources accepted by ParseRlimits to their
+// RLIMIT_* numbers
+var rlimitResources = map[string]int{
+	"as":      syscall.RLIMIT_AS,
+	"core":    syscall.RLIMIT_CORE,
+	"cpu":     syscall.RLIMIT_CPU,
+	"data":    syscall.RLIMIT_DATA,
+	"fsize":   syscall.RLIMIT_FSIZE,
+	"memlock": rlimitMemlock,
+	"nofile":  syscall.RLIMIT_NOFILE,
+	"nproc":   rlimitNproc,
+	"stack":   syscall.RLIMIT_STACK,
+}
+
+// limitsShimEnv is set to the shim's instructions when this executable is
+// re-executed to apply resource limits to a command
+const limitsShimEnv = "_TCSIGNAL_LIMITS_SHIM"
+
+// limitsShimFD is the pipe the shim reports a failure on. It is closed
+// when the command is executed.
+const limitsShimFD = 3
+
+// shimSpec tells the shim what to apply and what to execute
+type shimSpec struct {
+	Limits ResourceLimits
+	Path   string
+	// Credential is applied after the limits, so that root can raise them
+	// for a command run as another user
+	Credential *syscall.Credential
+}
+
+// limitsShim reports whether the re-executed command applied its limits
+type limitsShim struct {
+	errors     *os.File
+	errorsPipe *os.File
+}
+
+// wrapLimits makes cmd apply limits to itself before the command is
+// executed, so that it never runs without them: cmd runs this executable as
+// a shim that applies them and then executes the command in its place.
+func wrapLimits(cmd *exec.Cmd, limits ResourceLimits) (*limitsShim, error) {
+	if !limitsShimEnabled.Load() {
+		return nil, errors.New("signal.RunLimitsShim must be called at the start of main")
+	}
+
+	spec := shimSpec{Limits: limits, Path: cmd.Path}
+	if cmd.SysProcAttr != nil {
+		spec.Credential = cmd.SysProcAttr.Credential
+		cmd.SysProcAttr.Credential = nil
+	}
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Path = "/proc/self/exe"
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, limitsShimEnv+"="+string(encoded))
+	cmd.ExtraFiles = append([]*os.File{w}, cmd.ExtraFiles...)
+	return &limitsShim{errors: r, errorsPipe: w}, nil
+}
+
+// started waits until the shim has executed the command, returning the
+// error the shim failed with, if any
+func (s *limitsShim) started() error {
+	s.errorsPipe.Close()
+	defer s.errors.Close()
+	msg, err := io.ReadAll(s.errors)
+	if err != nil {
+		return err
+	}
+	if len(msg) > 0 {
+		return errors.New(string(msg))
+	}
+	return nil
+}
+
+// close releases the pipe if the shim could not be started
+func (s *limitsShim) close() {
+	s.errorsPipe.Close()
+	s.errors.Close()
+}
+
+// runLimitsShim, if this process is a shim, applies the limits and
+// credentials it was given and executes the command with the same arguments
+// and environment. It only returns, with true, if that failed, after
+// reporting the error to the executor.
+func runLimitsShim() bool {
+	encoded, ok := os.LookupEnv(limitsShimEnv)
+	if !ok {
+		return false
+	}
+	os.Unsetenv(limitsShimEnv)
+
+	err := execLimited(encoded)
+	errorsPipe := os.NewFile(limitsShimFD, "limits")
+	errorsPipe.WriteString(err.Error())
+	errorsPipe.Close()
+	return true
+}
+
+// execLimited executes the command described by encoded with its limits
+func execLimited(encoded string) error {
+	var spec shimSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		return fmt.Errorf("invalid resource limits: %w", err)
+	}
+	if err := applyLimits(spec.Limits); err != nil {
+		return fmt.Errorf("applying resource limits: %w", err)
+	}
+	if spec.Credential != nil {
+		if err := setCredentials(spec.Credential); err != nil {
+			return err
+		}
+	}
+
+	syscall.CloseOnExec(limitsShimFD)
+	err := syscall.Exec(spec.Path, os.Args, os.Environ())
+	return &os.PathError{Op: "fork/exec", Path: spec.Path, Err: err}
+}
+
+// applyLimits applies limits to this process. They are inherited by the
+// command it executes and every process the command starts.
+func applyLimits(limits ResourceLimits) error {
+	if limits.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, limits.Nice); err != nil {
+			return fmt.Errorf("setting nice %d: %w", limits.Nice, err)
+		}
+	}
+
+	if p := limits.IOPriority; p != nil {
+		if err := ioprioSet(p.Class, p.Level); err != nil {
+			return fmt.Errorf("setting I/O priority %s: %w", p, err)
+		}
+	}
+
+	for _, limit := range limits.Rlimits {
+		resource, ok := rlimitResources[limit.Resource]
+		if !ok {
+			return fmt.Errorf("unknown resource %q", limit.Resource)
+		}
+		// syscall.Setrlimit, unlike a raw prlimit, stops the runtime from
+		// restoring its original nofile limit on exec
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit.Soft, Max: limit.Hard}); err != nil {
+			return fmt.Errorf("setting %s limit: %w", limit.Resource, err)
+		}
+	}
+
+	if limits.OOMScoreAdj != nil {
+		if err := os.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(*limits.OOMScoreAdj)), 0); err != nil {
+			return fmt.Errorf("setting oom_score_adj: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setCredentials switches this process to the user, group and
+// supplementary groups in cred, as exec.Cmd would for the command
+func setCredentials(cred *syscall.Credential) error {
+	if !cred.NoSetGroups {
+		groups := make([]int, len(cred.Groups))
+		for i, gid := range cred.Groups {
+			groups[i] = int(gid)
+		}
+		if err := syscall.Setgroups(groups); err != nil {
+			return fmt.Errorf("setting groups: %w", err)
+		}
+	}
+	if err := syscall.Setgid(int(cred.Gid)); err != nil {
+		return fmt.Errorf("setting group: %w", err)
+	}
+	if err := syscall.Setuid(int(cred.Uid)); err != nil {
+		return fmt.Errorf("setting user: %w", err)
+	}
+	return nil
+}
+
+// ioprioSet sets the I/O scheduling class and level of this process. It
+// wraps ioprio_set(IOPRIO_WHO_PROCESS, 0, class << 13 | level).
+func ioprioSet(class string, level int) error {
+	const ioprioWhoProcess = 1
+	ioprio := ioClasses[class]<<13 | level
+	_, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(ioprio))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
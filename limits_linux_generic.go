@@ -0,0 +1,9 @@
+//go:build linux && !(mips || mipsle || mips64 || mips64le)
+
+package signal
+
+// RLIMIT_NPROC and RLIMIT_MEMLOCK are not defined by package syscall
+const (
+	rlimitNproc   = 6
+	rlimitMemlock = 8
+)
@@ -0,0 +1,9 @@
+//go:build linux && (mips || mipsle || mips64 || mips64le)
+
+package signal
+
+// RLIMIT_NPROC and RLIMIT_MEMLOCK are numbered differently on MIPS
+const (
+	rlimitNproc   = 8
+	rlimitMemlock = 9
+)
@@ -0,0 +1,112 @@
+package signal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestDefaultExecutor_Limits(t *testing.T) {
+	before, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		t.Fatalf("Failed to get priority: %v", err)
+	}
+
+	oomScoreAdj := 500
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Limits = ResourceLimits{
+		Nice:        5,
+		Rlimits:     []Rlimit{{Resource: "nofile", Soft: 256, Hard: 512}},
+		OOMScoreAdj: &oomScoreAdj,
+		IOPriority:  &IOPriority{Class: IOClassIdle},
+	}
+
+	// The limits are in place before the command runs
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "ulimit -Sn; ulimit -Hn; cat /proc/self/oom_score_adj; cut -d' ' -f19 /proc/self/stat; ionice",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d", result.ExitCode)
+	}
+
+	if got, want := strings.Fields(stdout.String()), []string{"256", "512", "500", "5", "idle"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected nofile 256/512, oom_score_adj 500, nice 5 and idle I/O, got %q", stdout.String())
+	}
+
+	// This process keeps its own priority
+	after, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		t.Fatalf("Failed to get priority: %v", err)
+	}
+	if after != before {
+		t.Errorf("Expected this process's priority to stay %d, got %d", before, after)
+	}
+}
+
+func TestDefaultExecutor_LimitsAsUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("running as another user requires root")
+	}
+
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Limits = ResourceLimits{Nice: -5}
+
+	// Raising priority needs root, so it is done before switching user
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "id -u; cut -d' ' -f19 /proc/self/stat",
+		User:    "nobody",
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d", result.ExitCode)
+	}
+
+	if got := strings.Join(strings.Fields(stdout.String()), " "); got != "65534 -5" {
+		t.Errorf("Expected nice -5 as nobody, got %q", got)
+	}
+}
+
+func TestDefaultExecutor_LimitsFailure(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Limits = ResourceLimits{
+		Rlimits: []Rlimit{{Resource: "nofile", Soft: RlimitInfinity, Hard: RlimitInfinity}},
+	}
+
+	// Raising the hard limit above the kernel maximum fails even as root
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "sleep 10"})
+	if err == nil || !strings.Contains(err.Error(), "applying resource limits: setting nofile limit") {
+		t.Fatalf("Expected a resource limit error, got: %v", err)
+	}
+	if result.Started {
+		t.Errorf("Expected the command to be reported as not started, got %+v", result)
+	}
+}
+
+func TestDefaultExecutor_LimitsWithoutShim(t *testing.T) {
+	limitsShimEnabled.Store(false)
+	defer limitsShimEnabled.Store(true)
+
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Limits = ResourceLimits{Nice: 5}
+
+	// Limits are never silently skipped when main did not install the shim
+	result, err := executor.Run(context.Background(), ExecSpec{Command: "true"})
+	if err == nil || !strings.Contains(err.Error(), "signal.RunLimitsShim must be called") {
+		t.Fatalf("Expected an error asking for RunLimitsShim, got: %v", err)
+	}
+	if result.Started {
+		t.Errorf("Expected the command not to be started, got %+v", result)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package signal
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// limitsShim is never created; resource limits are only applied on Linux
+type limitsShim struct{}
+
+func (s *limitsShim) started() error { return nil }
+
+func (s *limitsShim) close() {}
+
+// runLimitsShim does nothing; this process is never a shim
+func runLimitsShim() bool {
+	return false
+}
+
+// wrapLimits is not supported; resource limits are only applied on Linux
+func wrapLimits(cmd *exec.Cmd, limits ResourceLimits) (*limitsShim, error) {
+	return nil, fmt.Errorf("resource limits are only supported on Linux")
+}
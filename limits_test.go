@@ -0,0 +1,14 @@
+package signal
+
+import (
+	"os"
+	"testing"
+)
+
+// The test binary is re-executed as the shim that applies resource limits
+func TestMain(m *testing.M) {
+	if RunLimitsShim() {
+		os.Exit(127)
+	}
+	os.Exit(m.Run())
+}
@@ -3,15 +3,23 @@ package signal
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 )
 
 // MockExecutor for testing command execution
 type MockExecutor struct {
 	mu            sync.Mutex
-	calls         []string
+	calls         []ExecSpec
 	exitCode      int
+	signal        string
+	interrupted   string
 	err           error
+	output        string
+	delay         time.Duration
+	failCount     int
+	failExitCode  int
 	shouldFail    bool
 	customResults map[string]mockExecResult
 }
@@ -33,36 +41,99 @@ func (m *MockExecutor) SetExitCode(code int) {
 	m.exitCode = code
 }
 
+func (m *MockExecutor) SetSignal(signal string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signal = signal
+}
+
+func (m *MockExecutor) SetInterrupted(signal string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interrupted = signal
+}
+
 func (m *MockExecutor) SetError(err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.err = err
 }
 
+// SetOutput sets text written to the spec's Stdout on each run
+func (m *MockExecutor) SetOutput(output string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.output = output
+}
+
+// SetDelay makes each run take d, or until ctx is cancelled
+func (m *MockExecutor) SetDelay(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delay = d
+}
+
+// SetFailFirstNRuns makes the first n runs exit with exitCode
+func (m *MockExecutor) SetFailFirstNRuns(n, exitCode int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failCount = n
+	m.failExitCode = exitCode
+}
+
 func (m *MockExecutor) SetResultForCommand(cmd string, exitCode int, err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.customResults[cmd] = mockExecResult{exitCode: exitCode, err: err}
 }
 
-func (m *MockExecutor) Run(cmdLine string) (int, error) {
+func (m *MockExecutor) Run(ctx context.Context, spec ExecSpec) (ExecResult, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, spec)
+	output, delay := m.output, m.delay
+	m.mu.Unlock()
+
+	if output != "" && spec.Stdout != nil {
+		io.WriteString(spec.Stdout, output)
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.calls = append(m.calls, cmdLine)
+	if len(m.calls) <= m.failCount {
+		return ExecResult{Started: true, ExitCode: m.failExitCode}, nil
+	}
 
 	// Check for custom result first
-	if result, exists := m.customResults[cmdLine]; exists {
-		return result.exitCode, result.err
+	if result, exists := m.customResults[spec.String()]; exists {
+		return ExecResult{Started: result.err == nil, ExitCode: result.exitCode}, result.err
 	}
 
-	return m.exitCode, m.err
+	return ExecResult{Started: m.err == nil, ExitCode: m.exitCode, Signal: m.signal, Interrupted: m.interrupted}, m.err
 }
 
+// GetCalls returns the command of each call, as printed by ExecSpec.String
 func (m *MockExecutor) GetCalls() []string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	result := make([]string, len(m.calls))
+	for i, spec := range m.calls {
+		result[i] = spec.String()
+	}
+	return result
+}
+
+// GetSpecs returns the full spec of each call
+func (m *MockExecutor) GetSpecs() []ExecSpec {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]ExecSpec, len(m.calls))
 	copy(result, m.calls)
 	return result
 }
@@ -0,0 +1,323 @@
+package signal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// maxLogLineLength bounds the line buffered by LineLogWriter; longer lines
+// are logged in pieces
+const maxLogLineLength = 64 * 1024
+
+// maxLastLineLength bounds the line kept by LastLineWriter so it fits
+// comfortably in a message attribute
+const maxLastLineLength = 256
+
+// LastLineWriter remembers the most recent non-empty line written to it.
+// Carriage returns also end a line so progress output is tracked. It is
+// safe for concurrent use, e.g. as both stdout and stderr of a command.
+type LastLineWriter struct {
+	mu      sync.Mutex
+	line    []byte
+	partial []byte
+}
+
+func (w *LastLineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			w.partial = appendBounded(w.partial, data)
+			break
+		}
+
+		w.partial = appendBounded(w.partial, data[:i])
+		if len(bytes.TrimSpace(w.partial)) > 0 {
+			w.line = append(w.line[:0], w.partial...)
+		}
+		w.partial = w.partial[:0]
+		data = data[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Line returns the last non-empty line, including an unterminated line in
+// progress, trimmed of surrounding whitespace
+func (w *LastLineWriter) Line() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line := w.line
+	if len(bytes.TrimSpace(w.partial)) > 0 {
+		line = w.partial
+	}
+	return strings.ToValidUTF8(string(bytes.TrimSpace(line)), "")
+}
+
+// appendBounded appends data to buf, keeping at most maxLastLineLength bytes
+func appendBounded(buf, data []byte) []byte {
+	if room := maxLastLineLength - len(buf); len(data) > room {
+		data = data[:max(room, 0)]
+	}
+	return append(buf, data...)
+}
+
+// RotatingFile is an append-only log file that is rotated once it would
+// grow past MaxSize bytes. Rotated files are renamed path.1, path.2, ... up
+// to MaxBackups, with the oldest removed. It is safe for concurrent use.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile opens path for appending, creating it if needed
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		Path:       path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate %s: %w", f.Path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups up by one and starts a new file
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if f.MaxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", f.Path, f.MaxBackups))
+		for i := f.MaxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", f.Path, i), fmt.Sprintf("%s.%d", f.Path, i+1))
+		}
+		if err := os.Rename(f.Path, f.Path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.Path); err != nil {
+		return err
+	}
+
+	return f.open()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// LineLogWriter re-emits each line written to it as an info log entry with
+// the given fields, so command output is captured in structured logs.
+// Call Flush once the command has finished to log a final unterminated line.
+type LineLogWriter struct {
+	logger Logger
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+func NewLineLogWriter(logger Logger, fields ...zap.Field) *LineLogWriter {
+	return &LineLogWriter{logger: logger.With(fields...)}
+}
+
+func (w *LineLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, data...)
+			if len(w.partial) >= maxLogLineLength {
+				w.emit()
+			}
+			break
+		}
+
+		w.partial = append(w.partial, data[:i]...)
+		w.emit()
+		data = data[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush logs any buffered unterminated line
+func (w *LineLogWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.emit()
+	}
+}
+
+func (w *LineLogWriter) emit() {
+	line := strings.TrimRight(string(w.partial), "\r")
+	w.partial = w.partial[:0]
+	w.logger.Info("Command output", zap.String("line", line))
+}
+
+// PatternMatcher checks each line of a command's output against a success
+// and a failure pattern. Each stream needs its own writer from Writer so
+// lines from stdout and stderr are not mixed. It is safe for concurrent use.
+type PatternMatcher struct {
+	Success *regexp.Regexp
+	Failure *regexp.Regexp
+	// OnFailure is called once, with the matching line, the first time the
+	// failure pattern matches
+	OnFailure func(line string)
+
+	mu             sync.Mutex
+	successMatched bool
+	failureLine    string
+	failed         bool
+	writers        []*patternWriter
+}
+
+// Writer returns a writer for one output stream. Call Flush once the
+// command has finished to check a final unterminated line.
+func (m *PatternMatcher) Writer() io.Writer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := &patternWriter{matcher: m}
+	m.writers = append(m.writers, w)
+	return w
+}
+
+// Flush checks any buffered unterminated lines
+func (m *PatternMatcher) Flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.writers {
+		if len(w.partial) > 0 {
+			m.match(w.partial)
+			w.partial = w.partial[:0]
+		}
+	}
+}
+
+// SuccessMatched reports whether any line matched the success pattern
+func (m *PatternMatcher) SuccessMatched() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.successMatched
+}
+
+// FailureLine returns the first line that matched the failure pattern,
+// trimmed and truncated for a signal reason, and whether there was one
+func (m *PatternMatcher) FailureLine() (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failureLine, m.failed
+}
+
+// match checks a single line; m.mu must be held
+func (m *PatternMatcher) match(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if m.Success != nil && !m.successMatched && m.Success.Match(line) {
+		m.successMatched = true
+	}
+	if m.Failure != nil && !m.failed && m.Failure.Match(line) {
+		m.failed = true
+		m.failureLine = strings.ToValidUTF8(string(bytes.TrimSpace(appendBounded(nil, line))), "")
+		if m.OnFailure != nil {
+			m.OnFailure(m.failureLine)
+		}
+	}
+}
+
+type patternWriter struct {
+	matcher *PatternMatcher
+	partial []byte
+}
+
+func (w *patternWriter) Write(p []byte) (int, error) {
+	m := w.matcher
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, data...)
+			if len(w.partial) >= maxLogLineLength {
+				m.match(w.partial)
+				w.partial = w.partial[:0]
+			}
+			break
+		}
+
+		w.partial = append(w.partial, data[:i]...)
+		m.match(w.partial)
+		w.partial = w.partial[:0]
+		data = data[i+1:]
+	}
+
+	return len(p), nil
+}
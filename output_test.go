@@ -0,0 +1,195 @@
+package signal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLastLineWriter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{"empty", nil, ""},
+		{"single line", []string{"hello\n"}, "hello"},
+		{"last of several", []string{"one\ntwo\nthree\n"}, "three"},
+		{"skips blank lines", []string{"restoring\n\n  \n"}, "restoring"},
+		{"split writes", []string{"rest", "oring 4", "0%\n"}, "restoring 40%"},
+		{"unterminated line", []string{"done\nrestoring 50%"}, "restoring 50%"},
+		{"carriage returns", []string{"10%\r20%\r30%"}, "30%"},
+		{"trims whitespace", []string{"  indented\t\n"}, "indented"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var w LastLineWriter
+			for _, s := range tc.writes {
+				n, err := io.WriteString(&w, s)
+				if err != nil || n != len(s) {
+					t.Fatalf("Expected full write, got n=%d err=%v", n, err)
+				}
+			}
+
+			if line := w.Line(); line != tc.expected {
+				t.Errorf("Expected %q, got: %q", tc.expected, line)
+			}
+		})
+	}
+}
+
+func TestLastLineWriter_Truncates(t *testing.T) {
+	var w LastLineWriter
+	io.WriteString(&w, strings.Repeat("x", 1000)+"\n")
+
+	if line := w.Line(); len(line) != maxLastLineLength {
+		t.Errorf("Expected line truncated to %d bytes, got %d", maxLastLineLength, len(line))
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exec.log")
+
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := io.WriteString(f, line); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	// Each write would exceed 10 bytes, so every line starts a new file and
+	// only two backups are kept
+	expected := map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist, got: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Expected %s to contain %q, got: %q", name, content, data)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected oldest backup to be removed, got: %v", err)
+	}
+}
+
+func TestRotatingFile_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exec.log")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	f, err := OpenRotatingFile(path, 1024, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	io.WriteString(f, "this run\n")
+	f.Close()
+
+	if _, err := io.WriteString(f, "after close\n"); err == nil {
+		t.Error("Expected error writing to a closed file")
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "previous run\nthis run\n" {
+		t.Errorf("Expected output appended to existing file, got: %q", data)
+	}
+}
+
+func TestLineLogWriter(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := &ZapLogger{logger: zap.New(core)}
+
+	w := NewLineLogWriter(logger, zap.String("stream", "stdout"), zap.String("signal_id", "deploy-123"))
+	io.WriteString(w, "installing pack")
+	io.WriteString(w, "ages\r\ndone\nno newline")
+
+	if logs.Len() != 2 {
+		t.Fatalf("Expected 2 complete lines logged, got %d", logs.Len())
+	}
+
+	w.Flush()
+
+	entries := logs.All()
+	expected := []string{"installing packages", "done", "no newline"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+
+	for i, entry := range entries {
+		fields := entry.ContextMap()
+		if fields["line"] != expected[i] {
+			t.Errorf("Entry %d: expected line %q, got: %v", i, expected[i], fields["line"])
+		}
+		if fields["stream"] != "stdout" || fields["signal_id"] != "deploy-123" {
+			t.Errorf("Entry %d: expected stream and signal_id fields, got: %v", i, fields)
+		}
+	}
+}
+
+func TestPatternMatcher(t *testing.T) {
+	var failures []string
+	m := &PatternMatcher{
+		Success:   regexp.MustCompile(`^Installation complete`),
+		Failure:   regexp.MustCompile(`^ERROR:`),
+		OnFailure: func(line string) { failures = append(failures, line) },
+	}
+	stdout, stderr := m.Writer(), m.Writer()
+
+	io.WriteString(stdout, "Installing...\nInstallation ")
+	io.WriteString(stderr, "ERROR: disk full\r\n")
+	io.WriteString(stderr, "ERROR: again\n")
+	io.WriteString(stdout, "complete")
+
+	if m.SuccessMatched() {
+		t.Error("Expected unterminated line not to be matched before Flush")
+	}
+
+	m.Flush()
+
+	if !m.SuccessMatched() {
+		t.Error("Expected success pattern to match across writes")
+	}
+
+	line, failed := m.FailureLine()
+	if !failed || line != "ERROR: disk full" {
+		t.Errorf("Expected first failure line, got: %q, %v", line, failed)
+	}
+
+	if len(failures) != 1 || failures[0] != "ERROR: disk full" {
+		t.Errorf("Expected OnFailure to be called once, got: %q", failures)
+	}
+}
+
+func TestPatternMatcher_NoMatch(t *testing.T) {
+	m := &PatternMatcher{Failure: regexp.MustCompile(`ERROR`)}
+	io.WriteString(m.Writer(), "all good\nERR\nOR\n")
+	m.Flush()
+
+	if _, failed := m.FailureLine(); failed {
+		t.Error("Expected no failure match")
+	}
+
+	if m.SuccessMatched() {
+		t.Error("Expected no success match without a success pattern")
+	}
+}
@@ -0,0 +1,192 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Probe checks whether a service is ready
+type Probe interface {
+	// Check returns nil when the service is ready
+	Check(ctx context.Context) error
+	String() string
+}
+
+// HTTPProbe is ready when a GET of URL returns ExpectStatus, or any 2xx
+// status when ExpectStatus is zero
+type HTTPProbe struct {
+	URL          string
+	ExpectStatus int
+	Client       *http.Client // nil uses http.DefaultClient
+}
+
+func (p *HTTPProbe) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if p.ExpectStatus != 0 && resp.StatusCode != p.ExpectStatus {
+		return fmt.Errorf("got status %d, expected %d", resp.StatusCode, p.ExpectStatus)
+	}
+	if p.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *HTTPProbe) String() string {
+	return "http " + p.URL
+}
+
+// TCPProbe is ready when a TCP connection to Address succeeds
+type TCPProbe struct {
+	Address string
+}
+
+func (p *TCPProbe) Check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *TCPProbe) String() string {
+	return "tcp " + p.Address
+}
+
+// FileProbe is ready when Path exists
+type FileProbe struct {
+	Path string
+}
+
+func (p *FileProbe) Check(ctx context.Context) error {
+	_, err := os.Stat(p.Path)
+	return err
+}
+
+func (p *FileProbe) String() string {
+	return "file " + p.Path
+}
+
+// CommandProbe is ready when Command exits with code 0. Its output is
+// discarded.
+type CommandProbe struct {
+	Command  string
+	Executor Executor
+}
+
+func (p *CommandProbe) Check(ctx context.Context) error {
+	result, err := p.Executor.Run(ctx, ExecSpec{
+		Command: p.Command,
+		Stdout:  io.Discard,
+		Stderr:  io.Discard,
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("exited with code %d", result.ExitCode)
+	}
+	return nil
+}
+
+func (p *CommandProbe) String() string {
+	return "command " + p.Command
+}
+
+// ErrProbeTimeout is matched by the error WaitReady returns when probes are
+// not ready within its timeout
+var ErrProbeTimeout = errors.New("probe not ready in time")
+
+// probeTimeoutError reports a probe that was not ready in time. It matches
+// both ErrProbeTimeout and the probe's last error.
+type probeTimeoutError struct {
+	msg string
+	err error
+}
+
+func (e *probeTimeoutError) Error() string {
+	return e.msg
+}
+
+func (e *probeTimeoutError) Unwrap() []error {
+	return []error{ErrProbeTimeout, e.err}
+}
+
+// WaitReady polls each probe every interval until all of them are ready or
+// timeout elapses. Probes are waited for in order; the timeout covers all
+// of them.
+func WaitReady(ctx context.Context, probes []Probe, interval, timeout time.Duration, logger Logger) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for _, probe := range probes {
+		if err := waitForProbe(ctx, probe, interval, logger); err != nil {
+			if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return &probeTimeoutError{msg: fmt.Sprintf("%s not ready after %s: %v", probe, timeout, err), err: err}
+			}
+			return fmt.Errorf("%s not ready: %w", probe, err)
+		}
+	}
+	return nil
+}
+
+// minCheckTimeout is the least time a single probe check is given
+const minCheckTimeout = time.Second
+
+// waitForProbe polls a single probe until it is ready or ctx is done, in
+// which case it returns the last check error. Each check may take as long
+// as the interval, or minCheckTimeout if that is longer, so a service that
+// accepts connections but never responds is checked again.
+func waitForProbe(ctx context.Context, probe Probe, interval time.Duration, logger Logger) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		checkCtx, cancel := context.WithTimeout(ctx, max(interval, minCheckTimeout))
+		err := probe.Check(checkCtx)
+		cancel()
+		if err == nil {
+			logger.Info("Probe ready",
+				zap.Stringer("probe", probe),
+				zap.Int("attempts", attempt),
+				zap.Duration("elapsed", time.Since(start)))
+			return nil
+		}
+
+		logger.Debug("Probe not ready",
+			zap.Stringer("probe", probe),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+	}
+}
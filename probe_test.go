@@ -0,0 +1,160 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPProbe(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Unhealthy for the first two requests
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	probe := &HTTPProbe{URL: server.URL}
+	if err := probe.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "got status 503") {
+		t.Errorf("Expected status error, got: %v", err)
+	}
+
+	err := WaitReady(context.Background(), []Probe{probe}, 10*time.Millisecond, 5*time.Second, createTestLogger())
+	if err != nil {
+		t.Fatalf("Expected probe to become ready, got: %v", err)
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestHTTPProbe_ExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	probe := &HTTPProbe{URL: server.URL, ExpectStatus: http.StatusOK}
+	if err := probe.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "expected 200") {
+		t.Errorf("Expected status mismatch error, got: %v", err)
+	}
+
+	probe.ExpectStatus = http.StatusNoContent
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("Expected probe to pass, got: %v", err)
+	}
+}
+
+// Test that a server that accepts connections but never responds does not
+// hold up polling, even without a timeout
+func TestWaitReady_UnresponsiveServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var connections atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	probe := &HTTPProbe{URL: "http://" + listener.Addr().String()}
+	err = WaitReady(ctx, []Probe{probe}, 10*time.Millisecond, 0, createTestLogger())
+	if err == nil {
+		t.Fatal("Expected the probe not to become ready")
+	}
+
+	if connections.Load() < 2 {
+		t.Errorf("Expected the probe to be checked again after a check timed out, got %d connections", connections.Load())
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+
+	probe := &TCPProbe{Address: address}
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("Expected probe to pass, got: %v", err)
+	}
+
+	listener.Close()
+	if err := probe.Check(context.Background()); err == nil {
+		t.Error("Expected probe to fail after the listener closed")
+	}
+}
+
+func TestFileProbe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.ready")
+
+	// Create the file while the probe is polling
+	time.AfterFunc(50*time.Millisecond, func() {
+		os.WriteFile(path, nil, 0o600)
+	})
+
+	err := WaitReady(context.Background(), []Probe{&FileProbe{Path: path}}, 10*time.Millisecond, 5*time.Second, createTestLogger())
+	if err != nil {
+		t.Errorf("Expected file probe to become ready, got: %v", err)
+	}
+}
+
+func TestCommandProbe(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetResultForCommand("pg_isready", 2, nil)
+
+	probe := &CommandProbe{Command: "pg_isready", Executor: executor}
+	if err := probe.Check(context.Background()); err == nil || err.Error() != "exited with code 2" {
+		t.Errorf("Expected exit code error, got: %v", err)
+	}
+
+	executor.SetResultForCommand("pg_isready", 0, nil)
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("Expected probe to pass, got: %v", err)
+	}
+}
+
+func TestWaitReady_Timeout(t *testing.T) {
+	probes := []Probe{
+		&FileProbe{Path: filepath.Join(t.TempDir(), "never")},
+	}
+
+	start := time.Now()
+	err := WaitReady(context.Background(), probes, 10*time.Millisecond, 100*time.Millisecond, createTestLogger())
+	if err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "not ready after 100ms") || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected timeout error with the last probe error, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected WaitReady to stop at the timeout, took: %v", elapsed)
+	}
+}
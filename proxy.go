@@ -0,0 +1,87 @@
+package signal
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyFunc returns an http.Transport proxy function that sends HTTPS
+// requests through httpsProxy and bypasses the proxy for hosts matching
+// noProxy. Requests not covered by either fall back to the environment.
+func proxyFunc(httpsProxy *url.URL, noProxy string) func(*http.Request) (*url.URL, error) {
+	entries := splitNoProxy(noProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(req.URL, entries) {
+			return nil, nil
+		}
+		if httpsProxy != nil && req.URL.Scheme == "https" {
+			return httpsProxy, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}
+}
+
+// splitNoProxy splits a comma-separated NO_PROXY style list
+func splitNoProxy(noProxy string) []string {
+	var entries []string
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// matchNoProxy reports whether u matches any NO_PROXY entry. Entries may be
+// "*", an IP address, a CIDR block, or a domain (which also matches its
+// subdomains), each optionally followed by a port.
+func matchNoProxy(u *url.URL, entries []string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range entries {
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		if entryIP := net.ParseIP(strings.Trim(entryHost, "[]")); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		domain := strings.TrimPrefix(entryHost, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
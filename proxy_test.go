@@ -0,0 +1,70 @@
+package signal
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestMatchNoProxy(t *testing.T) {
+	entries := splitNoProxy(" .internal, example.com:8443,10.0.0.0/8, 169.254.169.254 ,[::1]")
+
+	testCases := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://sqs.internal", true},
+		{"https://internal", true},
+		{"https://example.com:8443", true},
+		{"https://example.com", false},
+		{"https://api.example.com:8443", true},
+		{"https://10.1.2.3", true},
+		{"https://11.1.2.3", false},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"https://[::1]:9324", true},
+		{"https://sqs.us-east-1.amazonaws.com", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			u, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatalf("Failed to parse URL: %v", err)
+			}
+
+			if got := matchNoProxy(u, entries); got != tc.expected {
+				t.Errorf("Expected matchNoProxy(%s) to be %v, got: %v", tc.url, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchNoProxy_Wildcard(t *testing.T) {
+	u, _ := url.Parse("https://sqs.us-east-1.amazonaws.com")
+	if !matchNoProxy(u, splitNoProxy("*")) {
+		t.Error("Expected * to match every host")
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+	proxy := proxyFunc(proxyURL, "vpce.internal")
+
+	req, _ := http.NewRequest("POST", "https://sqs.us-east-1.amazonaws.com/", nil)
+	got, err := proxy(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got == nil || got.String() != proxyURL.String() {
+		t.Errorf("Expected proxy %s, got: %v", proxyURL, got)
+	}
+
+	req, _ = http.NewRequest("POST", "https://sqs.vpce.internal/", nil)
+	got, err = proxy(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Expected no proxy for --no-proxy host, got: %v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package signal
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// eot is the terminal's end-of-file character, Ctrl-D
+const eot = 0x04
+
+// terminal is a pseudo-terminal a command runs under. The command's output
+// is copied from the terminal to an io.Writer, and input is typed into it.
+type terminal struct {
+	master *os.File
+	slave  *os.File
+	stdin  io.Reader
+	stdout io.Writer
+	output sync.WaitGroup
+}
+
+// attachTerminal runs cmd under a new pseudo-terminal as its controlling
+// terminal, stdin, stdout and stderr. cmd's Stdout receives the terminal's
+// output and stdin is typed into it.
+func attachTerminal(cmd *exec.Cmd, stdin io.Reader) (*terminal, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &terminal{master: master, slave: slave, stdin: stdin, stdout: cmd.Stdout}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	setControllingTerminal(cmd)
+	return t, nil
+}
+
+// start copies input and output once the command has started
+func (t *terminal) start() {
+	// Only the command may hold the terminal open, so reading it fails once
+	// every process using it has exited
+	t.slave.Close()
+
+	t.output.Add(1)
+	go func() {
+		defer t.output.Done()
+		io.Copy(t.stdout, t.master)
+	}()
+
+	// Input ends with end-of-file so a prompt does not wait forever. The
+	// copy is not waited for; it may block reading stdin after the command
+	// has exited.
+	go func() {
+		if t.stdin != nil {
+			io.Copy(t.master, t.stdin)
+		}
+		t.master.Write([]byte{eot})
+	}()
+}
+
+// wait waits up to delay for the command's output to be copied and closes
+// the terminal. A process the command started in the background may keep
+// the terminal open after the command has exited.
+func (t *terminal) wait(delay time.Duration) {
+	copied := make(chan struct{})
+	go func() {
+		t.output.Wait()
+		close(copied)
+	}()
+	select {
+	case <-copied:
+	case <-time.After(delay):
+	}
+	t.master.Close()
+	<-copied
+}
+
+// close releases a terminal whose command could not be started
+func (t *terminal) close() {
+	t.slave.Close()
+	t.master.Close()
+}
@@ -0,0 +1,97 @@
+package signal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// terminalRows and terminalCols are the size of a command's terminal
+const (
+	terminalRows = 24
+	terminalCols = 80
+)
+
+// openPTY opens a new pseudo-terminal pair from /dev/ptmx
+func openPTY() (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening pseudo-terminal: %w", err)
+	}
+
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlocking pseudo-terminal: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("getting pseudo-terminal number: %w", err)
+	}
+
+	slave, err = os.OpenFile("/dev/pts/"+strconv.FormatUint(uint64(n), 10), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("opening pseudo-terminal: %w", err)
+	}
+
+	// A zero-sized terminal breaks programs that format output to its width
+	size := [4]uint16{terminalRows, terminalCols, 0, 0}
+	if err := ioctl(slave, syscall.TIOCSWINSZ, unsafe.Pointer(&size)); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, fmt.Errorf("setting pseudo-terminal size: %w", err)
+	}
+
+	// Input such as passwords must not be echoed into the captured output
+	var termios syscall.Termios
+	if err := ioctl(slave, syscall.TCGETS, unsafe.Pointer(&termios)); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, fmt.Errorf("getting pseudo-terminal attributes: %w", err)
+	}
+	termios.Lflag &^= syscall.ECHO
+	if err := ioctl(slave, syscall.TCSETS, unsafe.Pointer(&termios)); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, fmt.Errorf("disabling pseudo-terminal echo: %w", err)
+	}
+
+	return master, slave, nil
+}
+
+// ioctl calls ioctl(2) on f without taking it out of non-blocking mode
+func ioctl(f *os.File, request uintptr, arg unsafe.Pointer) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setControllingTerminal starts cmd in a new session with its stdin as the
+// controlling terminal. The session's leader also leads a new process
+// group, which it cannot join with Setpgid.
+func setControllingTerminal(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+}
@@ -0,0 +1,116 @@
+package signal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultExecutor_TTY(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: `[ -t 0 ] && [ -t 1 ] && [ -t 2 ] || exit 3; echo out; echo err >&2; stty size; read answer; echo "answer=$answer"`,
+		Stdin:   strings.NewReader("yes\n"),
+		Stdout:  &stdout,
+		TTY:     true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (output %q)", result.ExitCode, stdout.String())
+	}
+
+	// The terminal ends lines with CRLF
+	output := strings.ReplaceAll(stdout.String(), "\r\n", "\n")
+	for _, want := range []string{"out\n", "err\n", "24 80\n", "answer=yes\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, output)
+		}
+	}
+}
+
+// Test that input, such as a password, is not echoed into the output
+func TestDefaultExecutor_TTYNoEcho(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: `printf 'Password: '; read password; echo; echo "length=${#password}"`,
+		Stdin:   strings.NewReader("hunter2-secret\n"),
+		Stdout:  &stdout,
+		TTY:     true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.ExitCode != 0 || !strings.Contains(stdout.String(), "length=14") {
+		t.Fatalf("Expected the password to be read, got exit code %d and output %q", result.ExitCode, stdout.String())
+	}
+	if strings.Contains(stdout.String(), "hunter2-secret") {
+		t.Errorf("Expected input not to be echoed, got %q", stdout.String())
+	}
+}
+
+func TestDefaultExecutor_TTYNoInput(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+
+	// A prompt reads end-of-file instead of waiting for input
+	var stdout bytes.Buffer
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: `if read answer; then echo "answer=$answer"; else echo eof; fi`,
+		Stdout:  &stdout,
+		TTY:     true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.ExitCode != 0 || !strings.Contains(stdout.String(), "eof") {
+		t.Errorf("Expected the prompt to read end-of-file, got exit code %d and output %q", result.ExitCode, stdout.String())
+	}
+}
+
+func TestDefaultExecutor_TTYTimeout(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Timeout = 200 * time.Millisecond
+
+	// The command leads a new session, and its process group is still
+	// terminated on timeout
+	start := time.Now()
+	_, err := executor.Run(context.Background(), ExecSpec{Command: "sleep 10 & wait", Stdout: io.Discard, TTY: true})
+	if !errors.Is(err, ErrExecTimeout) {
+		t.Fatalf("Expected ErrExecTimeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to be terminated promptly, took %s", elapsed)
+	}
+}
+
+func TestDefaultExecutor_TTYBackgroundProcess(t *testing.T) {
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.KillGrace = 200 * time.Millisecond
+
+	// The background process keeps the terminal open after the command exits
+	var stdout bytes.Buffer
+	start := time.Now()
+	result, err := executor.Run(context.Background(), ExecSpec{
+		Command: "setsid sleep 5 & echo started",
+		Stdout:  &stdout,
+		TTY:     true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the result soon after the command exited, took %v", elapsed)
+	}
+	if result.ExitCode != 0 || !strings.Contains(stdout.String(), "started") {
+		t.Errorf("Expected exit code 0 and output \"started\", got %d and %q", result.ExitCode, stdout.String())
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package signal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// openPTY is not supported; pseudo-terminals are only allocated on Linux
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, fmt.Errorf("running a command under a pseudo-terminal is only supported on Linux")
+}
+
+// setControllingTerminal does nothing; openPTY always fails
+func setControllingTerminal(cmd *exec.Cmd) {}
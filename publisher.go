@@ -6,13 +6,18 @@ import (
 )
 
 type PublishInput struct {
-	QueueURL       string
-	SignalID       string
-	InstanceID     string
-	Status         string
+	QueueURL   string
+	SignalID   string
+	InstanceID string
+	Status     Status
+	Reason     string
+	// Data is structured detail about the result, sent JSON-encoded in the
+	// "data" attribute when not empty
+	Data           map[string]any
 	Region         string
 	PublishTimeout time.Duration
 	Retries        int
+	AWS            AWSOptions
 }
 
 type Publisher interface {
@@ -3,6 +3,7 @@ package signal
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -31,7 +32,7 @@ func TestMockPublisher_Basic(t *testing.T) {
 		t.Errorf("Expected 1 call recorded, got: %d", len(calls))
 	}
 
-	if calls[0] != input {
+	if !reflect.DeepEqual(calls[0], input) {
 		t.Errorf("Expected call to match input")
 	}
 
@@ -196,10 +197,8 @@ func TestMockPublisher_MessageAttributes(t *testing.T) {
 func TestMockPublisher_StatusValues(t *testing.T) {
 	mock := NewMockPublisher()
 
-	testCases := []string{"SUCCESS", "FAILURE"}
-
-	for _, status := range testCases {
-		t.Run("Status_"+status, func(t *testing.T) {
+	for _, status := range Statuses {
+		t.Run("Status_"+string(status), func(t *testing.T) {
 			input := PublishInput{
 				QueueURL: "test-queue",
 				SignalID: "test-signal",
@@ -295,6 +294,73 @@ func TestSQSPublisher_Creation(t *testing.T) {
 	}
 }
 
+func TestBuildSendMessageInput_Reason(t *testing.T) {
+	input := PublishInput{
+		QueueURL:   "test-queue",
+		SignalID:   "test-signal",
+		InstanceID: "i-1234567890abcdef0",
+		Status:     "FAILURE",
+	}
+
+	// No reason attribute when the reason is empty
+	sqsInput, err := buildSendMessageInput(input)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := sqsInput.MessageAttributes["reason"]; ok {
+		t.Error("Expected no reason attribute for empty reason")
+	}
+
+	input.Reason = "command timed out after 5m0s"
+	sqsInput, err = buildSendMessageInput(input)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	attr, ok := sqsInput.MessageAttributes["reason"]
+	if !ok {
+		t.Fatal("Expected reason attribute to be set")
+	}
+	if *attr.StringValue != input.Reason {
+		t.Errorf("Expected reason %q, got: %q", input.Reason, *attr.StringValue)
+	}
+}
+
+func TestBuildSendMessageInput_Data(t *testing.T) {
+	input := PublishInput{
+		QueueURL:   "test-queue",
+		SignalID:   "test-signal",
+		InstanceID: "i-1234567890abcdef0",
+		Status:     "SUCCESS",
+	}
+
+	// No data attribute when there is no data
+	sqsInput, err := buildSendMessageInput(input)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := sqsInput.MessageAttributes["data"]; ok {
+		t.Error("Expected no data attribute for empty data")
+	}
+
+	input.Data = map[string]any{"attempts": 2, "version": "1.4.2"}
+	sqsInput, err = buildSendMessageInput(input)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	attr, ok := sqsInput.MessageAttributes["data"]
+	if !ok {
+		t.Fatal("Expected data attribute to be set")
+	}
+	if expected := `{"attempts":2,"version":"1.4.2"}`; *attr.StringValue != expected {
+		t.Errorf("Expected data %s, got: %s", expected, *attr.StringValue)
+	}
+
+	input.Data = map[string]any{"invalid": make(chan int)}
+	if _, err := buildSendMessageInput(input); err == nil {
+		t.Error("Expected an error for data that cannot be encoded")
+	}
+}
+
 func TestMockPublisher_RetryConfiguration(t *testing.T) {
 	mock := NewMockPublisher()
 
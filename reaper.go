@@ -0,0 +1,61 @@
+package signal
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// Reaper reaps orphaned processes re-parented to this process, which must
+// be done when running as PID 1. Commands run by a DefaultExecutor using
+// the Reaper are left for the executor to wait for. Only one Reaper should
+// run per process. Linux only; elsewhere it does nothing.
+type Reaper struct {
+	logger Logger
+
+	// mu is held while reaping and while starting a command, so a command
+	// that exits immediately is registered before the reaper can see it
+	mu      sync.Mutex
+	waiting map[int]struct{}
+	wake    chan struct{}
+	stop    func()
+}
+
+// StartReaper starts reaping orphaned processes until Stop is called
+func StartReaper(logger Logger) *Reaper {
+	r := &Reaper{
+		logger:  logger,
+		waiting: make(map[int]struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+	r.stop = r.start()
+	return r
+}
+
+// Stop stops reaping. Commands still running are unaffected.
+func (r *Reaper) Stop() {
+	r.stop()
+}
+
+// startCommand starts cmd and registers it so it is left for cmd.Wait
+func (r *Reaper) startCommand(cmd *exec.Cmd) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	r.waiting[cmd.Process.Pid] = struct{}{}
+	return nil
+}
+
+// release unregisters a command once cmd.Wait has returned. Orphans that
+// exited while the command's exit was pending are reaped now.
+func (r *Reaper) release(pid int) {
+	r.mu.Lock()
+	delete(r.waiting, pid)
+	r.mu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
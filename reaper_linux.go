@@ -0,0 +1,85 @@
+package signal
+
+import (
+	"os"
+	ossignal "os/signal"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go.uber.org/zap"
+)
+
+// start reaps exited processes whenever SIGCHLD is received or a command
+// is released, until the returned function is called
+func (r *Reaper) start() (stop func()) {
+	sigchld := make(chan os.Signal, 1)
+	ossignal.Notify(sigchld, syscall.SIGCHLD)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigchld:
+			case <-r.wake:
+			}
+			r.reapOrphans()
+		}
+	}()
+
+	return func() {
+		ossignal.Stop(sigchld)
+		close(done)
+		wg.Wait()
+	}
+}
+
+// reapOrphans reaps exited children until it finds none, or finds one an
+// executor is waiting for. waitid returns the same child until it has been
+// waited for, so the rest are reaped once that command is released.
+func (r *Reaper) reapOrphans() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		// Peek at the next exited child without reaping it
+		exited, err := waitidNoWait()
+		if err != nil || exited == 0 {
+			return
+		}
+		if _, ok := r.waiting[exited]; ok {
+			return
+		}
+
+		var status syscall.WaitStatus
+		if _, err := syscall.Wait4(exited, &status, syscall.WNOHANG, nil); err != nil {
+			return
+		}
+		r.logger.Debug("Reaped orphaned process", zap.Int("pid", exited), zap.Int("exit_code", status.ExitStatus()))
+	}
+}
+
+// waitidNoWait returns the PID of an exited child without reaping it, or 0
+// if there is none. It wraps waitid(P_ALL, 0, &info, WEXITED|WNOHANG|WNOWAIT).
+func waitidNoWait() (int, error) {
+	const pAll = 0
+
+	// siginfo_t is 128 bytes; si_pid follows three ints, aligned to 8 bytes
+	// on 64-bit platforms
+	var info [128]byte
+	_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pAll, 0, uintptr(unsafe.Pointer(&info[0])),
+		syscall.WEXITED|syscall.WNOHANG|syscall.WNOWAIT, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	offset := 12
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		offset = 16
+	}
+	return int(*(*int32)(unsafe.Pointer(&info[offset]))), nil
+}
@@ -0,0 +1,88 @@
+package signal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// prSetChildSubreaper makes orphaned descendants re-parent to this process
+// as they would to PID 1
+const prSetChildSubreaper = 36
+
+func TestReaper_CommandProbeWhileSupervising(t *testing.T) {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		t.Skipf("Cannot become a child subreaper: %v", errno)
+	}
+	defer syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 0, 0)
+
+	reaper := StartReaper(createTestLogger())
+	defer reaper.Stop()
+	executor := NewDefaultExecutor(createTestLogger())
+	executor.Reaper = reaper
+
+	// The service leaves an orphan behind and exits with a code the reaper
+	// must not take from it
+	var stdout bytes.Buffer
+	service := make(chan error, 1)
+	go func() {
+		result, err := executor.Run(context.Background(), ExecSpec{
+			Command: "sleep 0.05 & echo $!; sleep 0.5; exit 3",
+			Stdout:  &stdout,
+		})
+		if err == nil && result.ExitCode != 3 {
+			err = fmt.Errorf("exit code %d", result.ExitCode)
+		}
+		service <- err
+	}()
+
+	// Probes run while the service runs; the reaper must leave each for its
+	// executor to wait for
+	probe := &CommandProbe{Command: "true", Executor: executor}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 25 {
+				if err := probe.Check(context.Background()); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Probe failed: %v", err)
+	}
+
+	if err := <-service; err != nil {
+		t.Fatalf("Expected the service to exit with code 3, got: %v", err)
+	}
+
+	// The orphan has been reaped, not left a zombie
+	orphan, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatalf("Expected the orphan's PID, got %q", stdout.String())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat("/proc/" + strconv.Itoa(orphan)); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected orphan %d to be reaped", orphan)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package signal
+
+// start does nothing; zombie reaping is only supported on Linux
+func (r *Reaper) start() (stop func()) {
+	return func() {}
+}
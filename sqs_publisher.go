@@ -2,6 +2,8 @@ package signal
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
@@ -37,17 +39,43 @@ func (p *SQSPublisher) Publish(ctx context.Context, input PublishInput) error {
 		configOptions = append(configOptions, config.WithRegion(input.Region))
 	}
 
-	awsCfg, err := config.LoadDefaultConfig(ctx, configOptions...)
+	awsCfg, err := loadAWSConfig(ctx, input.AWS, configOptions...)
 	if err != nil {
 		return err
 	}
 
-	client := sqs.NewFromConfig(awsCfg)
+	client := sqs.NewFromConfig(awsCfg, input.AWS.sqsOptions)
 
 	// Create context with publish timeout
 	publishCtx, cancel := context.WithTimeout(ctx, input.PublishTimeout)
 	defer cancel()
 
+	sqsInput, err := buildSendMessageInput(input)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.SendMessage(publishCtx, sqsInput)
+	if err != nil {
+		p.Logger.Error("Failed to send SQS message",
+			zap.Int("retries", input.Retries),
+			zap.String("signal_id", input.SignalID),
+			zap.String("instance_id", input.InstanceID),
+			zap.Error(err))
+		return err
+	}
+
+	p.Logger.Info("SQS message sent successfully",
+		zap.String("message_id", *result.MessageId),
+		zap.String("signal_id", input.SignalID),
+		zap.String("instance_id", input.InstanceID),
+		zap.Stringer("status", input.Status))
+
+	return nil
+}
+
+// buildSendMessageInput renders the SendMessage request for a signal
+func buildSendMessageInput(input PublishInput) (*sqs.SendMessageInput, error) {
 	sqsInput := &sqs.SendMessageInput{
 		QueueUrl:    aws.String(input.QueueURL),
 		MessageBody: aws.String("tcsignal-aws message"),
@@ -62,26 +90,29 @@ func (p *SQSPublisher) Publish(ctx context.Context, input PublishInput) error {
 			},
 			"status": {
 				DataType:    aws.String("String"),
-				StringValue: aws.String(input.Status),
+				StringValue: aws.String(string(input.Status)),
 			},
 		},
 	}
 
-	result, err := client.SendMessage(publishCtx, sqsInput)
-	if err != nil {
-		p.Logger.Error("Failed to send SQS message",
-			zap.Int("retries", input.Retries),
-			zap.String("signal_id", input.SignalID),
-			zap.String("instance_id", input.InstanceID),
-			zap.Error(err))
-		return err
+	// SQS rejects empty attribute values, so only send a reason when present
+	if input.Reason != "" {
+		sqsInput.MessageAttributes["reason"] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(input.Reason),
+		}
 	}
 
-	p.Logger.Info("SQS message sent successfully",
-		zap.String("message_id", *result.MessageId),
-		zap.String("signal_id", input.SignalID),
-		zap.String("instance_id", input.InstanceID),
-		zap.String("status", input.Status))
+	if len(input.Data) > 0 {
+		data, err := json.Marshal(input.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode signal data: %w", err)
+		}
+		sqsInput.MessageAttributes["data"] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(string(data)),
+		}
+	}
 
-	return nil
+	return sqsInput, nil
 }
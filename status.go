@@ -0,0 +1,65 @@
+package signal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Status is the outcome a signal reports. Waiters should stop waiting on
+// any status other than IN_PROGRESS.
+type Status string
+
+const (
+	// StatusSuccess means the work finished successfully
+	StatusSuccess Status = "SUCCESS"
+	// StatusFailure means the work itself failed, e.g. the command exited
+	// with a failure code or a check did not pass
+	StatusFailure Status = "FAILURE"
+	// StatusInProgress is a heartbeat: the work is still running and a
+	// final status will follow
+	StatusInProgress Status = "IN_PROGRESS"
+	// StatusTimeout means the work did not finish in time and was given
+	// up on, e.g. --exec-timeout or --wait-timeout elapsed
+	StatusTimeout Status = "TIMEOUT"
+	// StatusCancelled means the work was aborted by an operator or the
+	// system, e.g. tcsignal-aws received SIGTERM while a command ran
+	StatusCancelled Status = "CANCELLED"
+	// StatusSkipped means the work was not done, e.g. a step after a failed
+	// step, or a command reporting there was nothing to do. It is not a
+	// failure.
+	StatusSkipped Status = "SKIPPED"
+)
+
+// Statuses are the statuses tcsignal-aws sends itself. --status-map may
+// send other, custom statuses.
+var Statuses = []Status{StatusSuccess, StatusFailure, StatusInProgress, StatusTimeout, StatusCancelled, StatusSkipped}
+
+// ParseStatus returns the status named s, which must be one of Statuses
+func ParseStatus(s string) (Status, error) {
+	status := Status(s)
+	if !slices.Contains(Statuses, status) {
+		names := make([]string, len(Statuses))
+		for i, known := range Statuses {
+			names[i] = string(known)
+		}
+		return "", fmt.Errorf("unknown status %q (expected one of %s)", s, strings.Join(names, ", "))
+	}
+	return status, nil
+}
+
+func (s Status) String() string {
+	return string(s)
+}
+
+// Terminal reports whether the status is final, i.e. anything but
+// IN_PROGRESS
+func (s Status) Terminal() bool {
+	return s != StatusInProgress
+}
+
+// Failed reports whether the status means the work did not succeed:
+// FAILURE, TIMEOUT or CANCELLED. Custom statuses are not failures.
+func (s Status) Failed() bool {
+	return s == StatusFailure || s == StatusTimeout || s == StatusCancelled
+}
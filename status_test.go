@@ -0,0 +1,49 @@
+package signal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	for _, status := range Statuses {
+		got, err := ParseStatus(string(status))
+		if err != nil {
+			t.Errorf("Expected %s to parse, got: %v", status, err)
+		}
+		if got != status {
+			t.Errorf("Expected %s, got: %s", status, got)
+		}
+	}
+
+	for _, invalid := range []string{"", "success", "RETRY"} {
+		if _, err := ParseStatus(invalid); err == nil || !strings.Contains(err.Error(), "unknown status") {
+			t.Errorf("Expected an unknown status error for %q, got: %v", invalid, err)
+		}
+	}
+}
+
+func TestStatus_Semantics(t *testing.T) {
+	testCases := []struct {
+		status   Status
+		terminal bool
+		failed   bool
+	}{
+		{StatusSuccess, true, false},
+		{StatusFailure, true, true},
+		{StatusInProgress, false, false},
+		{StatusTimeout, true, true},
+		{StatusCancelled, true, true},
+		{StatusSkipped, true, false},
+		{"REBOOT_REQUIRED", true, false}, // custom --status-map status
+	}
+
+	for _, tc := range testCases {
+		if got := tc.status.Terminal(); got != tc.terminal {
+			t.Errorf("Expected %s Terminal() to be %v, got %v", tc.status, tc.terminal, got)
+		}
+		if got := tc.status.Failed(); got != tc.failed {
+			t.Errorf("Expected %s Failed() to be %v, got %v", tc.status, tc.failed, got)
+		}
+	}
+}
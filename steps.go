@@ -0,0 +1,83 @@
+package signal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stepNamePattern matches step names, which become part of per-step signal IDs
+var stepNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Step is one named command in a multi-step run
+type Step struct {
+	Name string `yaml:"name"`
+	// Run is a shell command line, run with sh -c
+	Run string `yaml:"run"`
+	// Args is an argv run directly without a shell; used when Run is empty
+	Args []string `yaml:"args"`
+}
+
+// ExecSpec returns the command to run for the step
+func (s Step) ExecSpec() ExecSpec {
+	return ExecSpec{
+		Command: s.Run,
+		Args:    s.Args,
+	}
+}
+
+// stepsFile is the layout of a --steps file
+type stepsFile struct {
+	Steps []Step `yaml:"steps"`
+}
+
+// LoadSteps reads and validates a YAML steps file
+func LoadSteps(path string) ([]Step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file stepsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := validateSteps(file.Steps); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return file.Steps, nil
+}
+
+// validateSteps checks that steps are uniquely named and each has exactly
+// one of run or args
+func validateSteps(steps []Step) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("no steps defined")
+	}
+
+	seen := make(map[string]bool, len(steps))
+	for i, step := range steps {
+		if !stepNamePattern.MatchString(step.Name) {
+			return fmt.Errorf("step %d: invalid name %q (use letters, digits, '.', '_' or '-')", i+1, step.Name)
+		}
+		if seen[step.Name] {
+			return fmt.Errorf("step %d: duplicate name %q", i+1, step.Name)
+		}
+		seen[step.Name] = true
+
+		if (step.Run == "") == (len(step.Args) == 0) {
+			return fmt.Errorf("step %q: exactly one of run or args must be set", step.Name)
+		}
+	}
+
+	return nil
+}
+
+// StepSignalID returns the signal ID used for a step when signalling per step
+func StepSignalID(id string, step Step) string {
+	return id + "/" + step.Name
+}
@@ -0,0 +1,76 @@
+package signal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeStepsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "steps.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write steps file: %v", err)
+	}
+	return path
+}
+
+func TestLoadSteps(t *testing.T) {
+	path := writeStepsFile(t, `
+steps:
+  - name: packages
+    run: ./install-packages.sh && ./configure.sh
+  - name: app
+    args: ["/opt/app/install", "--name", "web server"]
+`)
+
+	steps, err := LoadSteps(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Step{
+		{Name: "packages", Run: "./install-packages.sh && ./configure.sh"},
+		{Name: "app", Args: []string{"/opt/app/install", "--name", "web server"}},
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, steps)
+	}
+
+	if spec := steps[1].ExecSpec(); spec.Command != "" || !reflect.DeepEqual(spec.Args, expected[1].Args) {
+		t.Errorf("Expected argv spec, got: %+v", spec)
+	}
+}
+
+func TestLoadSteps_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"empty", "steps: []\n", "no steps defined"},
+		{"malformed", "steps: [\n", "failed to parse"},
+		{"missing name", "steps:\n  - run: ./a.sh\n", "invalid name"},
+		{"name with slash", "steps:\n  - name: a/b\n    run: ./a.sh\n", "invalid name"},
+		{"duplicate name", "steps:\n  - name: a\n    run: ./a.sh\n  - name: a\n    run: ./b.sh\n", "duplicate name"},
+		{"no command", "steps:\n  - name: a\n", "exactly one of run or args"},
+		{"both commands", "steps:\n  - name: a\n    run: ./a.sh\n    args: [./a.sh]\n", "exactly one of run or args"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadSteps(writeStepsFile(t, tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestStepSignalID(t *testing.T) {
+	if id := StepSignalID("deploy-123", Step{Name: "packages"}); id != "deploy-123/packages" {
+		t.Errorf("Expected deploy-123/packages, got: %s", id)
+	}
+}